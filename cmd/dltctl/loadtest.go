@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/client"
+)
+
+func login(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	email := fs.String("email", os.Getenv("DLT_EMAIL"), "email of the user")
+	password := fs.String("password", os.Getenv("DLT_PASSWORD"), "password of the user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	result, err := c.Login(api.LoginRequest{Email: *email, Password: *password})
+	if err != nil {
+		return err
+	}
+	fmt.Println(result.Token)
+	return nil
+}
+
+func loadTestCommand(c *client.Client, args []string) error {
+	if len(args) == 0 {
+		return errors.New("lt needs one of create, list, get, stop, rerun, follow, results, queue, priority, cancel")
+	}
+	switch args[0] {
+	case "create":
+		return createLoadTest(c, args[1:])
+	case "list":
+		return listLoadTests(c, args[1:])
+	case "get":
+		return getLoadTest(c, args[1:])
+	case "stop":
+		return stopLoadTest(c, args[1:])
+	case "rerun":
+		return rerunLoadTest(c, args[1:])
+	case "follow":
+		return followLoadTest(c, args[1:])
+	case "results":
+		return loadTestResults(c, args[1:])
+	case "queue":
+		return listQueue(c, args[1:])
+	case "priority":
+		return reorderQueuedLoadTest(c, args[1:])
+	case "cancel":
+		return cancelQueuedLoadTest(c, args[1:])
+	}
+	return fmt.Errorf("unknown lt command %s", args[0])
+}
+
+// runFlags - flags of commands which can wait for the load test to finish
+// and check its results
+type runFlags struct {
+	follow  *bool
+	output  *string
+	asserts *assertions
+}
+
+func addRunFlags(fs *flag.FlagSet) runFlags {
+	f := runFlags{
+		follow:  fs.Bool("follow", false, "follow the load test until it finishes"),
+		output:  fs.String("o", outputTable, "output format of the results, table, json or csv"),
+		asserts: &assertions{},
+	}
+	fs.Var(f.asserts, "assert", "slo the results must meet, eg. success_percent>=99 or avg_latency_ms<250, repeatable")
+	return f
+}
+
+// afterStart - follow a started load test when asked to, any assertion
+// implies following since results are only final once the test finished
+func (f runFlags) afterStart(c *client.Client, lt *api.LoadTest) error {
+	if !*f.follow && len(*f.asserts) == 0 {
+		return printLoadTests(*f.output, []api.LoadTest{*lt})
+	}
+	fmt.Fprintf(os.Stderr, "started load test %s\n", lt.ID)
+	return follow(c, lt.ID, *f.output, *f.asserts)
+}
+
+func createLoadTest(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("lt create", flag.ContinueOnError)
+	description := fs.String("description", "", "description of the load test")
+	tps := fs.Float64("tps", 0, "transactions per second")
+	duration := fs.Int("duration", 0, "duration in seconds")
+	logic := fs.String("logic", "", "plugin logic of the load test")
+	logicFile := fs.String("logic-file", "", "file to read the plugin logic from, - for stdin")
+	namespace := fs.String("namespace", "", "namespace to run the load test in")
+	priority := fs.Int("priority", 0, "queue priority, higher starts first")
+	nodeGroups := fs.String("node-groups", "", "comma separated node groups to run on, all idle ones by default")
+	rf := addRunFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *logicFile != "" {
+		data, err := readFile(*logicFile)
+		if err != nil {
+			return err
+		}
+		*logic = string(data)
+	}
+
+	lt, err := c.CreateLoadTest(api.CreateLoadTestRequest{
+		Description: *description,
+		TPS:         *tps,
+		Duration:    *duration,
+		Logic:       *logic,
+		Namespace:   *namespace,
+		Priority:    *priority,
+		NodeGroups:  splitList(*nodeGroups),
+	})
+	if err != nil {
+		return err
+	}
+	return rf.afterStart(c, lt)
+}
+
+func rerunLoadTest(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("lt rerun", flag.ContinueOnError)
+	rf := addRunFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := idArg(fs)
+	if err != nil {
+		return err
+	}
+
+	previous, err := c.GetLoadTest(id)
+	if err != nil {
+		return err
+	}
+	lt, err := c.CreateLoadTest(api.CreateLoadTestRequest{
+		Description: previous.Description,
+		TPS:         previous.TPS,
+		Duration:    previous.Duration,
+		Logic:       previous.Logic,
+		Namespace:   previous.Namespace,
+		Priority:    previous.Priority,
+		NodeGroups:  previous.NodeGroups,
+	})
+	if err != nil {
+		return err
+	}
+	return rf.afterStart(c, lt)
+}
+
+func listLoadTests(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("lt list", flag.ContinueOnError)
+	status := fs.String("status", "", "only load tests with this status")
+	createdBy := fs.String("created-by", "", "only load tests started by this user id")
+	text := fs.String("q", "", "search in the description")
+	since := fs.Duration("since", 0, "only load tests started in this time, eg. 24h")
+	limit := fs.Int64("limit", 20, "number of load tests")
+	offset := fs.Int64("offset", 0, "number of load tests to skip")
+	sort := fs.String("sort", "", "field to sort by, prefix with - for descending")
+	output := fs.String("o", outputTable, "output format, table, json or csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter := client.LoadTestFilter{
+		Status:    *status,
+		CreatedBy: *createdBy,
+		Text:      *text,
+	}
+	if *since > 0 {
+		filter.From = time.Now().Add(-*since)
+	}
+	page, err := c.ListLoadTests(filter, client.ListOptions{Offset: *offset, Limit: *limit, Sort: *sort})
+	if err != nil {
+		return err
+	}
+	if err := printLoadTests(*output, page.Items); err != nil {
+		return err
+	}
+	if *output == outputTable {
+		fmt.Fprintf(os.Stderr, "showing %d of %d\n", len(page.Items), page.Total)
+	}
+	return nil
+}
+
+func getLoadTest(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("lt get", flag.ContinueOnError)
+	output := fs.String("o", outputTable, "output format, table, json or csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := idArg(fs)
+	if err != nil {
+		return err
+	}
+
+	lt, err := c.GetLoadTest(id)
+	if err != nil {
+		return err
+	}
+	return printLoadTests(*output, []api.LoadTest{*lt})
+}
+
+func stopLoadTest(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("lt stop", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := idArg(fs)
+	if err != nil {
+		return err
+	}
+
+	if err := c.StopLoadTest(id, ""); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "stopping load test %s\n", id)
+	return nil
+}
+
+func listQueue(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("lt queue", flag.ContinueOnError)
+	namespace := fs.String("namespace", "", "only load tests queued in this namespace")
+	output := fs.String("o", outputTable, "output format, table, json or csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	queued, err := c.ListQueue(*namespace)
+	if err != nil {
+		return err
+	}
+	return printQueue(*output, queued)
+}
+
+func reorderQueuedLoadTest(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("lt priority", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("%s needs the id of the load test and its priority", fs.Name())
+	}
+	priority, err := strconv.Atoi(fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("invalid priority %s", fs.Arg(1))
+	}
+
+	lt, err := c.ReorderQueuedLoadTest(fs.Arg(0), priority)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "load test %s has priority %d\n", lt.ID, lt.Priority)
+	return nil
+}
+
+func cancelQueuedLoadTest(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("lt cancel", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := idArg(fs)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.CancelQueuedLoadTest(id); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "cancelled load test %s\n", id)
+	return nil
+}
+
+func followLoadTest(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("lt follow", flag.ContinueOnError)
+	rf := addRunFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := idArg(fs)
+	if err != nil {
+		return err
+	}
+	return follow(c, id, *rf.output, *rf.asserts)
+}
+
+func loadTestResults(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("lt results", flag.ContinueOnError)
+	output := fs.String("o", outputTable, "output format, table, json or csv")
+	asserts := &assertions{}
+	fs.Var(asserts, "assert", "slo the results must meet, eg. success_percent>=99 or avg_latency_ms<250, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	id, err := idArg(fs)
+	if err != nil {
+		return err
+	}
+
+	results, err := c.GetLoadTestResults(id)
+	if err != nil {
+		return err
+	}
+	if err := printResults(*output, results); err != nil {
+		return err
+	}
+	return asserts.check(results)
+}
+
+// follow - show the live stats of a load test until it stops running, then
+// print its results and check them against the assertions
+func follow(c *client.Client, id string, output string, asserts assertions) error {
+	var summary *client.LoadTestResults
+	err := c.StreamLoadTest(id, func(e client.StreamEvent) error {
+		switch e.Type {
+		case api.EventStatus:
+			status, err := e.Status()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "\nload test %s %s\n", id, status)
+		case api.EventStats:
+			stats, err := e.Stats()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "\r%s  tps %.1f  requests %d  errors %.2f%%  p50 %.0fms  p99 %.0fms  node groups %d ",
+				time.Now().Format("15:04:05"), stats.CurrentTPS, stats.TotalRequests, stats.ErrorRate*100,
+				stats.LatencyMs.P50, stats.LatencyMs.P99, len(stats.NodeGroups))
+		case api.EventHealth:
+			change := api.HealthChange{}
+			if err := json.Unmarshal(e.Data, &change); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "\nnode group %s is %s\n", change.NodeGroupID, change.HealthStatus)
+		case api.EventSummary:
+			var err error
+			summary, err = e.Summary()
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if summary == nil {
+		return fmt.Errorf("stream of load test %s ended before its summary", id)
+	}
+	if err := printResults(output, summary); err != nil {
+		return err
+	}
+	return asserts.check(summary)
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func idArg(fs *flag.FlagSet) (string, error) {
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("%s needs the id of the load test", fs.Name())
+	}
+	return fs.Arg(0), nil
+}
+
+func readFile(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mridulganga/dlt-manager/pkg/client"
+)
+
+const usage = `dltctl - command line client of the dlt manager
+
+usage:
+  dltctl [global flags] <command> [flags] [args]
+
+commands:
+  login                 log in and print a session token
+  lt create             queue a load test, it starts once node groups are idle
+  lt list               list load tests
+  lt get <id>           show a load test
+  lt stop <id>          stop a load test
+  lt rerun <id>         start a new load test with the settings of another one
+  lt follow <id>        follow a running load test until it finishes
+  lt results <id>       show the results of a load test
+  lt queue              list queued load tests in the order they start
+  lt priority <id> <n>  change the priority of a queued load test
+  lt cancel <id>        cancel a queued load test
+  ng list               list node groups with their health
+  ng nodes <id>         show the nodes of a node group and their history
+  ng health <id>        show the health timeline and uptime of a node group
+  ng config <id>        show the desired config of a node group and if it runs it
+  ng configure <id>     replace the desired config of a node group
+  ng approve <id>       approve a node group which registered itself
+  ng cordon <id>        stop giving a node group new load tests
+  ng uncordon <id>      give a cordoned node group load tests again
+  ng drain <id>         stop the load tests of a node group and cordon it
+
+global flags, defaulting to the environment:
+  -url       manager url ($DLT_URL, http://localhost:8080)
+  -token     session token ($DLT_TOKEN)
+  -api-key   api key ($DLT_API_KEY)
+  -project   project id ($DLT_PROJECT)
+
+exit codes:
+  0 success, 1 error, 2 slo assertion failed
+`
+
+const (
+	exitError       = 1
+	exitAssertFails = 2
+)
+
+// errAssertFailed - returned by commands whose slo assertions did not hold
+var errAssertFailed = errors.New("slo assertions failed")
+
+func main() {
+	global := flag.NewFlagSet("dltctl", flag.ExitOnError)
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	url := global.String("url", env("DLT_URL", "http://localhost:8080"), "manager url")
+	token := global.String("token", os.Getenv("DLT_TOKEN"), "session token")
+	apiKey := global.String("api-key", os.Getenv("DLT_API_KEY"), "api key")
+	project := global.String("project", os.Getenv("DLT_PROJECT"), "project id")
+	global.Parse(os.Args[1:])
+
+	c := client.NewClient(*url)
+	c.SetToken(*token)
+	c.SetAPIKey(*apiKey)
+	c.SetProject(*project)
+
+	err := run(c, global.Args())
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errAssertFailed):
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(exitAssertFails)
+	default:
+		fmt.Fprintln(os.Stderr, "error:", err.Error())
+		os.Exit(exitError)
+	}
+}
+
+func run(c *client.Client, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return errors.New("no command given")
+	}
+	switch args[0] {
+	case "login":
+		return login(c, args[1:])
+	case "lt", "loadtest", "loadtests":
+		return loadTestCommand(c, args[1:])
+	case "ng", "ngs", "nodegroups":
+		return nodeGroupCommand(c, args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	}
+	return fmt.Errorf("unknown command %s", args[0])
+}
+
+func env(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/client"
+)
+
+func nodeGroupCommand(c *client.Client, args []string) error {
+	if len(args) == 0 {
+		return errors.New("ng needs one of list, nodes, health, config, configure, approve, cordon, uncordon, drain")
+	}
+	switch args[0] {
+	case "list":
+		return listNodeGroups(c, args[1:])
+	case "nodes":
+		return nodeGroupNodes(c, args[1:])
+	case "health":
+		return nodeGroupHealth(c, args[1:])
+	case "config":
+		return nodeGroupConfig(c, args[1:])
+	case "configure":
+		return configureNodeGroup(c, args[1:])
+	case "approve":
+		return nodeGroupAction(c, "ng approve", c.ApproveNodeGroup, args[1:])
+	case "cordon":
+		return nodeGroupAction(c, "ng cordon", c.CordonNodeGroup, args[1:])
+	case "uncordon":
+		return nodeGroupAction(c, "ng uncordon", c.UncordonNodeGroup, args[1:])
+	case "drain":
+		return nodeGroupAction(c, "ng drain", c.DrainNodeGroup, args[1:])
+	}
+	return fmt.Errorf("unknown ng command %s", args[0])
+}
+
+func listNodeGroups(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("ng list", flag.ContinueOnError)
+	namespace := fs.String("namespace", "", "only node groups of this namespace")
+	healthy := fs.String("healthy", "", "only healthy (true) or unhealthy (false) node groups")
+	health := fs.String("health", "", "only node groups with this health, healthy, stale or unhealthy")
+	pending := fs.Bool("pending", false, "only node groups waiting for approval")
+	cordoned := fs.String("cordoned", "", "only cordoned (true) or schedulable (false) node groups")
+	drift := fs.Bool("drift", false, "only node groups which don't run their desired config")
+	limit := fs.Int64("limit", 50, "number of node groups")
+	offset := fs.Int64("offset", 0, "number of node groups to skip")
+	sort := fs.String("sort", "", "field to sort by, prefix with - for descending")
+	output := fs.String("o", outputTable, "output format, table, json or csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	filter := client.NodeGroupFilter{HealthStatus: *health}
+	if *namespace != "" {
+		filter.Namespace = namespace
+	}
+	if *healthy != "" {
+		b, err := strconv.ParseBool(*healthy)
+		if err != nil {
+			return fmt.Errorf("invalid -healthy %s", *healthy)
+		}
+		filter.IsHealthy = &b
+	}
+	if *pending {
+		filter.Pending = pending
+	}
+	if *cordoned != "" {
+		b, err := strconv.ParseBool(*cordoned)
+		if err != nil {
+			return fmt.Errorf("invalid -cordoned %s", *cordoned)
+		}
+		filter.Cordoned = &b
+	}
+	if *drift {
+		filter.ConfigDrift = drift
+	}
+
+	page, err := c.ListNodeGroups(filter, client.ListOptions{Offset: *offset, Limit: *limit, Sort: *sort})
+	if err != nil {
+		return err
+	}
+	if err := printNodeGroups(*output, page.Items); err != nil {
+		return err
+	}
+	if *output == outputTable {
+		fmt.Fprintf(os.Stderr, "showing %d of %d\n", len(page.Items), page.Total)
+	}
+	return nil
+}
+
+func nodeGroupNodes(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("ng nodes", flag.ContinueOnError)
+	history := fs.Int64("history", 20, "number of latest node events to show")
+	output := fs.String("o", outputTable, "output format, table, json or csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%s needs the id of the node group", fs.Name())
+	}
+
+	result, err := c.GetNodeGroupNodes(fs.Arg(0), *history)
+	if err != nil {
+		return err
+	}
+	if *output == outputJSON {
+		return printRows(*output, result, nil, nil)
+	}
+	if err := printNodes(*output, result.Nodes); err != nil {
+		return err
+	}
+	if *output == outputTable && len(result.History) > 0 {
+		fmt.Println()
+		return printNodeEvents(*output, result.History)
+	}
+	return nil
+}
+
+func nodeGroupHealth(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("ng health", flag.ContinueOnError)
+	since := fs.Duration("since", 7*24*time.Hour, "how far back to report")
+	output := fs.String("o", outputTable, "output format, table, json or csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%s needs the id of the node group", fs.Name())
+	}
+
+	report, err := c.GetNodeGroupHealth(fs.Arg(0), time.Now().Add(-*since), time.Time{})
+	if err != nil {
+		return err
+	}
+	if *output == outputJSON {
+		return printRows(*output, report, nil, nil)
+	}
+	if err := printHealthTimeline(*output, report.Timeline); err != nil {
+		return err
+	}
+	if *output == outputTable {
+		uptime := "unknown"
+		if report.UptimePercent != nil {
+			uptime = strconv.FormatFloat(*report.UptimePercent, 'f', 2, 64) + "%"
+		}
+		fmt.Fprintf(os.Stderr, "uptime %s\n", uptime)
+	}
+	return nil
+}
+
+func nodeGroupConfig(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("ng config", flag.ContinueOnError)
+	output := fs.String("o", outputTable, "output format, table, json or csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%s needs the id of the node group", fs.Name())
+	}
+
+	status, err := c.GetNodeGroupConfig(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return printConfigStatus(*output, status)
+}
+
+// configureNodeGroup - replaces the whole desired config, settings which are
+// not given go back to the node group defaults
+func configureNodeGroup(c *client.Client, args []string) error {
+	fs := flag.NewFlagSet("ng configure", flag.ContinueOnError)
+	heartbeatInterval := fs.Duration("heartbeat-interval", 0, "heartbeat interval, 0 for the node group default")
+	batchSize := fs.Int("result-batch-size", 0, "results per heartbeat, 0 for the node group default")
+	logLevel := fs.String("log-level", "", "debug, info, warn or error")
+	maxConcurrency := fs.Int("max-concurrency", 0, "concurrency limit, 0 for the node group default")
+	output := fs.String("o", outputTable, "output format, table, json or csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%s needs the id of the node group", fs.Name())
+	}
+
+	status, err := c.SetNodeGroupConfig(fs.Arg(0), api.SetNodeGroupConfigRequest{
+		HeartbeatInterval: int(heartbeatInterval.Seconds()),
+		ResultBatchSize:   *batchSize,
+		LogLevel:          *logLevel,
+		MaxConcurrency:    *maxConcurrency,
+	})
+	if err != nil {
+		return err
+	}
+	return printConfigStatus(*output, status)
+}
+
+// nodeGroupAction - run an action on the node group given by id and print it
+func nodeGroupAction(c *client.Client, name string, action func(id string) (*api.NodeGroup, error), args []string) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	output := fs.String("o", outputTable, "output format, table, json or csv")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("%s needs the id of the node group", fs.Name())
+	}
+
+	ng, err := action(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return printNodeGroups(*output, []api.NodeGroup{*ng})
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/client"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+// printRows - write a header and rows as table or csv, or value as json
+func printRows(output string, value any, header []string, rows [][]string) error {
+	switch output {
+	case outputJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(value)
+	case outputCSV:
+		w := csv.NewWriter(os.Stdout)
+		w.Write(header)
+		w.WriteAll(rows)
+		return w.Error()
+	case outputTable:
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, strings.ToUpper(strings.Join(header, "\t")))
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return w.Flush()
+	}
+	return fmt.Errorf("unknown output format %s, use table, json or csv", output)
+}
+
+func printLoadTests(output string, lts []api.LoadTest) error {
+	header := []string{"id", "status", "tps", "duration", "namespace", "created_by", "start_time", "description"}
+	rows := [][]string{}
+	for _, lt := range lts {
+		rows = append(rows, []string{
+			lt.ID,
+			lt.Status,
+			strconv.FormatFloat(lt.TPS, 'f', -1, 64),
+			strconv.Itoa(lt.Duration),
+			lt.Namespace,
+			lt.CreatedBy,
+			formatTime(lt.StartTime),
+			lt.Description,
+		})
+	}
+	return printRows(output, lts, header, rows)
+}
+
+func printQueue(output string, lts []api.LoadTest) error {
+	header := []string{"position", "id", "priority", "namespace", "tps", "duration", "queued_at", "description"}
+	rows := [][]string{}
+	for i, lt := range lts {
+		rows = append(rows, []string{
+			strconv.Itoa(i + 1),
+			lt.ID,
+			strconv.Itoa(lt.Priority),
+			lt.Namespace,
+			strconv.FormatFloat(lt.TPS, 'f', -1, 64),
+			strconv.Itoa(lt.Duration),
+			formatTime(lt.QueuedAt),
+			lt.Description,
+		})
+	}
+	return printRows(output, lts, header, rows)
+}
+
+func printNodeGroups(output string, ngs []api.NodeGroup) error {
+	header := []string{"id", "health", "state", "nodes", "max_tps", "topic", "namespace", "shared", "last_health_check"}
+	rows := [][]string{}
+	for _, ng := range ngs {
+		rows = append(rows, []string{
+			ng.ID,
+			ng.Health(),
+			nodeGroupState(ng),
+			strconv.Itoa(len(ng.Nodes)),
+			formatCapacity(ng.Capacity),
+			ng.Topic,
+			ng.Namespace,
+			strconv.FormatBool(ng.Shared),
+			formatTime(ng.LastHealthCheck),
+		})
+	}
+	return printRows(output, ngs, header, rows)
+}
+
+func printNodes(output string, nodes []api.Node) error {
+	header := []string{"node_id", "status", "version", "capacity", "load_test_id", "last_seen", "joined_at", "left_at"}
+	rows := [][]string{}
+	for _, node := range nodes {
+		rows = append(rows, []string{
+			node.NodeID,
+			node.Status,
+			node.Version,
+			strconv.Itoa(node.Capacity),
+			node.LoadTestID,
+			formatTime(node.LastSeen),
+			formatTime(node.JoinedAt),
+			formatTime(node.LeftAt),
+		})
+	}
+	return printRows(output, nodes, header, rows)
+}
+
+func printNodeEvents(output string, events []api.NodeEvent) error {
+	header := []string{"timestamp", "node_id", "event", "status"}
+	rows := [][]string{}
+	for _, event := range events {
+		rows = append(rows, []string{formatTime(event.Timestamp), event.NodeID, event.Event, event.Status})
+	}
+	return printRows(output, events, header, rows)
+}
+
+func printHealthTimeline(output string, timeline []api.HealthPeriod) error {
+	header := []string{"status", "start", "end", "duration", "load_tests"}
+	rows := [][]string{}
+	for _, period := range timeline {
+		rows = append(rows, []string{
+			period.Status,
+			formatTime(period.Start),
+			formatTime(period.End),
+			period.End.Sub(period.Start).Round(time.Second).String(),
+			strings.Join(period.LoadTests, " "),
+		})
+	}
+	return printRows(output, timeline, header, rows)
+}
+
+func printConfigStatus(output string, status *api.NodeGroupConfigStatus) error {
+	header := []string{"setting", "value"}
+	rows := [][]string{
+		{"node_group_id", status.NodeGroupID},
+		{"version", strconv.FormatInt(status.Desired.Version, 10)},
+		{"applied_version", strconv.FormatInt(status.AppliedVersion, 10)},
+		{"in_sync", strconv.FormatBool(status.InSync)},
+		{"heartbeat_interval", strconv.Itoa(status.Desired.HeartbeatInterval)},
+		{"result_batch_size", strconv.Itoa(status.Desired.ResultBatchSize)},
+		{"log_level", status.Desired.LogLevel},
+		{"max_concurrency", strconv.Itoa(status.Desired.MaxConcurrency)},
+		{"updated_at", formatTime(status.Desired.UpdatedAt)},
+	}
+	return printRows(output, status, header, rows)
+}
+
+// printResults - one metric per row so that csv output is easy to consume
+func printResults(output string, r *client.LoadTestResults) error {
+	header := []string{"metric", "value"}
+	rows := [][]string{
+		{"load_test_id", r.LoadTestID},
+		{"start_time", formatTime(r.StartTime)},
+		{"end_time", formatTime(r.EndTime)},
+		{"duration", strconv.Itoa(r.Duration)},
+		{"tps", strconv.FormatFloat(r.TPS, 'f', -1, 64)},
+		{"total_requests", strconv.Itoa(r.TotalRequests)},
+		{"success_count", strconv.Itoa(r.SuccessCount)},
+		{"failure_count", strconv.Itoa(r.FailureCount)},
+		{"success_percent", strconv.FormatFloat(r.PercentSuccessful(), 'f', 2, 64)},
+		{"avg_latency_ms", strconv.FormatFloat(r.AvgLatencyMs, 'f', 2, 64)},
+	}
+	codes := []string{}
+	for code := range r.TopFailures {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		rows = append(rows, []string{"failure_" + code, r.TopFailures[code]})
+	}
+	return printRows(output, r, header, rows)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func formatCapacity(capacity api.NodeGroupCapacity) string {
+	if !capacity.IsKnown() {
+		return "-"
+	}
+	return strconv.FormatFloat(capacity.MaxTPS, 'f', -1, 64)
+}
+
+// nodeGroupState - whether the node group takes new load tests
+func nodeGroupState(ng api.NodeGroup) string {
+	switch {
+	case ng.Pending:
+		return "pending"
+	case ng.Draining:
+		return "draining"
+	case ng.Cordoned:
+		return "cordoned"
+	}
+	return "active"
+}
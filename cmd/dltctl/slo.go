@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mridulganga/dlt-manager/pkg/client"
+)
+
+// metrics - result values assertions can be made on
+var metrics = map[string]func(r *client.LoadTestResults) float64{
+	"success_percent": func(r *client.LoadTestResults) float64 { return r.PercentSuccessful() },
+	"avg_latency_ms":  func(r *client.LoadTestResults) float64 { return r.AvgLatencyMs },
+	"total_requests":  func(r *client.LoadTestResults) float64 { return float64(r.TotalRequests) },
+	"success_count":   func(r *client.LoadTestResults) float64 { return float64(r.SuccessCount) },
+	"failure_count":   func(r *client.LoadTestResults) float64 { return float64(r.FailureCount) },
+}
+
+// operators - longest first so that >= is not read as >
+var operators = []string{">=", "<=", "==", "!=", ">", "<"}
+
+type assertion struct {
+	metric   string
+	operator string
+	value    float64
+}
+
+func parseAssertion(s string) (assertion, error) {
+	for _, op := range operators {
+		metric, value, found := strings.Cut(s, op)
+		if !found {
+			continue
+		}
+		metric = strings.TrimSpace(metric)
+		if _, ok := metrics[metric]; !ok {
+			return assertion{}, fmt.Errorf("unknown metric %s in assertion %s", metric, s)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return assertion{}, fmt.Errorf("invalid value in assertion %s", s)
+		}
+		return assertion{metric: metric, operator: op, value: v}, nil
+	}
+	return assertion{}, fmt.Errorf("assertion %s needs one of %s", s, strings.Join(operators, " "))
+}
+
+func (a assertion) holds(actual float64) bool {
+	switch a.operator {
+	case ">=":
+		return actual >= a.value
+	case "<=":
+		return actual <= a.value
+	case "==":
+		return actual == a.value
+	case "!=":
+		return actual != a.value
+	case ">":
+		return actual > a.value
+	case "<":
+		return actual < a.value
+	}
+	return false
+}
+
+func (a assertion) String() string {
+	return fmt.Sprintf("%s%s%v", a.metric, a.operator, a.value)
+}
+
+// assertions - repeatable -assert flag
+type assertions []assertion
+
+func (as *assertions) String() string {
+	parts := []string{}
+	for _, a := range *as {
+		parts = append(parts, a.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+func (as *assertions) Set(s string) error {
+	a, err := parseAssertion(s)
+	if err != nil {
+		return err
+	}
+	*as = append(*as, a)
+	return nil
+}
+
+// check - errAssertFailed listing every assertion the results don't meet
+func (as assertions) check(r *client.LoadTestResults) error {
+	failed := []string{}
+	for _, a := range as {
+		actual := metrics[a.metric](r)
+		if !a.holds(actual) {
+			failed = append(failed, fmt.Sprintf("%s (was %v)", a, actual))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %s", errAssertFailed, strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mridulganga/dlt-manager/pkg/client"
+)
+
+func TestParseAssertion(t *testing.T) {
+	tests := []struct {
+		s    string
+		want assertion
+	}{
+		{"success_percent>=99.5", assertion{"success_percent", ">=", 99.5}},
+		{" avg_latency_ms < 250 ", assertion{"avg_latency_ms", "<", 250}},
+		{"failure_count==0", assertion{"failure_count", "==", 0}},
+		{"total_requests>1000", assertion{"total_requests", ">", 1000}},
+		{"success_count!=0", assertion{"success_count", "!=", 0}},
+		{"avg_latency_ms<=100", assertion{"avg_latency_ms", "<=", 100}},
+	}
+	for _, tt := range tests {
+		got, err := parseAssertion(tt.s)
+		if err != nil || got != tt.want {
+			t.Errorf("parseAssertion(%q) = %+v %v, want %+v", tt.s, got, err, tt.want)
+		}
+	}
+
+	for _, s := range []string{"", "success_percent", "success_percent=99", "p99>=1", "success_percent>=high"} {
+		if a, err := parseAssertion(s); err == nil {
+			t.Errorf("parseAssertion(%q) = %+v, want an error", s, a)
+		}
+	}
+}
+
+func TestAssertionHolds(t *testing.T) {
+	tests := []struct {
+		operator string
+		actual   float64
+		want     bool
+	}{
+		{">=", 10, true}, {">=", 9, false},
+		{"<=", 10, true}, {"<=", 11, false},
+		{"==", 10, true}, {"==", 11, false},
+		{"!=", 11, true}, {"!=", 10, false},
+		{">", 11, true}, {">", 10, false},
+		{"<", 9, true}, {"<", 10, false},
+	}
+	for _, tt := range tests {
+		a := assertion{metric: "total_requests", operator: tt.operator, value: 10}
+		if got := a.holds(tt.actual); got != tt.want {
+			t.Errorf("%v with %v = %v, want %v", a, tt.actual, got, tt.want)
+		}
+	}
+}
+
+func TestAssertionsCheck(t *testing.T) {
+	var as assertions
+	for _, s := range []string{"success_percent>=99", "avg_latency_ms<200"} {
+		if err := as.Set(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := as.Set("bogus>1"); err == nil {
+		t.Fatal("invalid assertion accepted")
+	}
+	if len(as) != 2 {
+		t.Fatalf("got %d assertions, want 2", len(as))
+	}
+
+	if err := as.check(&client.LoadTestResults{TotalRequests: 1000, SuccessCount: 999, AvgLatencyMs: 120}); err != nil {
+		t.Errorf("passing results failed: %v", err)
+	}
+	err := as.check(&client.LoadTestResults{TotalRequests: 1000, SuccessCount: 950, AvgLatencyMs: 120})
+	if !errors.Is(err, errAssertFailed) {
+		t.Errorf("failing results returned %v, want errAssertFailed", err)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/mridulganga/dlt-manager/pkg/mqttlib"
+	"github.com/mridulganga/dlt-manager/pkg/sim"
+	"github.com/mridulganga/dlt-manager/pkg/transport"
+	"github.com/sirupsen/logrus"
+)
+
+const usage = `dltsim - simulated node groups for trying the dlt manager locally
+
+usage:
+  dltsim [flags]
+
+node groups created through the api are simulated by giving their id with -id
+and their topic with -topic. Node groups which don't exist yet register
+themselves with -enrollment-token and listen on ng/<id> in the namespace.
+
+flags, the transport defaulting to the environment of the manager:
+`
+
+func main() {
+	fs := flag.NewFlagSet("dltsim", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	transportName := fs.String("transport", env("TRANSPORT", "mqtt"), "mqtt or nats")
+	mqttHost := fs.String("mqtt-host", env("MQTT_HOST", "127.0.0.1"), "mqtt broker host")
+	mqttPort := fs.Int("mqtt-port", envInt("MQTT_PORT", 1883), "mqtt broker port")
+	natsUrl := fs.String("nats-url", env("NATS_URL", "nats://127.0.0.1:4222"), "nats url")
+	namespace := fs.String("namespace", "", "namespace of the node groups")
+	id := fs.String("id", "sim", "node group id, numbered when simulating several")
+	topic := fs.String("topic", "", "topic of the node group, numbered when simulating several, defaults to ng/<id> in the namespace")
+	count := fs.Int("node-groups", 1, "number of node groups")
+	nodes := fs.Int("nodes", 2, "nodes per node group")
+	interval := fs.Duration("interval", sim.DefaultHeartbeatInterval, "heartbeat interval")
+	latencyMean := fs.Float64("latency-ms", 100, "mean latency of requests in ms")
+	latencyStdDev := fs.Float64("latency-stddev-ms", 50, "standard deviation of the latency in ms")
+	errorRate := fs.Float64("error-rate", 0.01, "share of requests which fail")
+	dropRate := fs.Float64("drop-rate", 0, "share of heartbeats which are dropped")
+	crashAfter := fs.Duration("crash-after", 0, "crash this long after every start, 0 never crashes")
+	crashFor := fs.Duration("crash-for", sim.DefaultHeartbeatInterval*6, "how long a crashed node group stays down")
+	maxTps := fs.Float64("max-tps", 0, "capacity to report, 0 reports none")
+	multiTenant := fs.Bool("multi-tenant", false, "run several load tests at once")
+	enrollmentToken := fs.String("enrollment-token", os.Getenv("DLT_ENROLLMENT_TOKEN"), "register with this enrollment token")
+	fs.Parse(os.Args[1:])
+
+	var t transport.Transport
+	switch *transportName {
+	case "mqtt":
+		m := mqttlib.NewMqtt(*mqttHost, *mqttPort)
+		go m.Connect()
+		t = m
+	case "nats":
+		n, err := transport.NewNats(*natsUrl)
+		if err != nil {
+			logrus.Fatal(err.Error())
+		}
+		t = n
+	default:
+		logrus.Fatalf("unknown transport %s", *transportName)
+	}
+	t.WaitUntilConnected()
+	defer t.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	wg := sync.WaitGroup{}
+	for i := 1; i <= *count; i++ {
+		ngId, ngTopic := *id, *topic
+		if *count > 1 {
+			ngId = fmt.Sprintf("%s-%d", *id, i)
+			if ngTopic != "" {
+				ngTopic = fmt.Sprintf("%s-%d", *topic, i)
+			}
+		}
+		g := sim.NewNodeGroup(t, sim.Config{
+			NodeGroupID:       ngId,
+			Namespace:         transport.Namespace(*namespace),
+			Topic:             ngTopic,
+			Nodes:             *nodes,
+			HeartbeatInterval: *interval,
+			Latency:           sim.Latency{MeanMs: *latencyMean, StdDevMs: *latencyStdDev},
+			ErrorRate:         *errorRate,
+			DropRate:          *dropRate,
+			CrashAfter:        *crashAfter,
+			CrashFor:          *crashFor,
+			MaxTPS:            *maxTps,
+			MultiTenant:       *multiTenant,
+			EnrollmentToken:   *enrollmentToken,
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := g.Run(ctx); err != nil {
+				logrus.Errorf("node group %s stopped %v", ngId, err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func env(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -1,24 +1,51 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
 	"os"
 	"strconv"
+	"time"
 
-	mqtt "github.com/eclipse/paho.mqtt.golang"
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/auth"
 	"github.com/mridulganga/dlt-manager/pkg/db"
+	"github.com/mridulganga/dlt-manager/pkg/health"
+	"github.com/mridulganga/dlt-manager/pkg/live"
 	"github.com/mridulganga/dlt-manager/pkg/mqttlib"
+	"github.com/mridulganga/dlt-manager/pkg/openapi"
+	"github.com/mridulganga/dlt-manager/pkg/proc"
+	"github.com/mridulganga/dlt-manager/pkg/sched"
+	"github.com/mridulganga/dlt-manager/pkg/transport"
 	"github.com/mridulganga/dlt-manager/pkg/view"
 	"github.com/sirupsen/logrus"
-	"go.mongodb.org/mongo-driver/bson"
 )
 
 const (
-	MQTT_HOST = "MQTT_HOST"
-	MQTT_PORT = "MQTT_PORT"
-	MONGO     = "MONGO"
+	MQTT_HOST        = "MQTT_HOST"
+	MQTT_PORT        = "MQTT_PORT"
+	MQTT_EMBEDDED    = "MQTT_EMBEDDED"
+	MQTT_LISTEN_HOST = "MQTT_LISTEN_HOST"
+	NAMESPACES       = "NAMESPACES"
+	AUTH_SECRET      = "AUTH_SECRET"
+	SESSION_TTL      = "SESSION_TTL"
+	MONGO            = "MONGO"
+	TRANSPORT        = "TRANSPORT"
+	NATS_URL         = "NATS_URL"
+	NATS_EMBEDDED    = "NATS_EMBEDDED"
+	NATS_HOST        = "NATS_HOST"
+	NATS_PORT        = "NATS_PORT"
+	HB_INTERVAL      = "HEARTBEAT_INTERVAL"
+	HB_STALE_AFTER   = "HEARTBEAT_STALE_AFTER"
+	HB_UNHEALTHY     = "HEARTBEAT_UNHEALTHY_AFTER"
+	TRANSPORT_MQTT   = "mqtt"
+	TRANSPORT_NATS   = "nats"
+	TRANSPORT_INPROC = "inproc"
 )
 
 func main() {
@@ -27,97 +54,161 @@ func main() {
 		panic("Error loading .env file")
 	}
 
-	mqttHost := os.Getenv(MQTT_HOST)
-	mqttPort, _ := strconv.Atoi(os.Getenv(MQTT_PORT))
 	mongo := os.Getenv(MONGO)
 	dbName := os.Getenv("DB_NAME")
-	logrus.Infof("mqtt host %s port %v", mqttHost, mqttPort)
 
-	lastActiveLoadTestId := ""
-
-	m := mqttlib.NewMqtt(mqttHost, mqttPort)
-	go m.Connect()
+	m, err := newTransport()
+	if err != nil {
+		panic(err)
+	}
 	m.WaitUntilConnected()
 
 	d, err := db.NewDatabase(mongo, dbName)
 	if err != nil {
 		panic(err)
 	}
+	if err := d.CreateIndexes(); err != nil {
+		panic(err)
+	}
 
-	m.Sub("manager", func(client mqtt.Client, message mqtt.Message) {
-		data := db.NGHeartbeat{}
-		json.Unmarshal(message.Payload(), &data)
-
-		switch data.Action {
-		case "ng_update":
-			logrus.Info("processing ng_update")
-			isNGHealthy := data.NodeGroupStatus == "healthy"
-
-			// update ng health db collection
-			err := d.UpdateNodeGroupHealth(data.NodeGroupID, isNGHealthy)
-			if err != nil {
-				logrus.Errorf("error while UpdateNodeGroupHealth %v", err.Error())
-			}
-
-			// update node list if ng healthy
-			if isNGHealthy {
-				d.UpdateNodeGroup(data.NodeGroupID, bson.M{"nodes": data.Nodes})
-			}
-
-			// check if lt active
-			if data.IsLoadTestActive {
-				// get lt results and put in db
-				nodeUpdates := db.NodeUpdates{}
-				json.Unmarshal([]byte(data.NodeUpdates), &nodeUpdates)
-				d.PushLoadTestResult(data.LoadTestId, nodeUpdates)
-				lastActiveLoadTestId = data.LoadTestId
-			}
-
-			// check if lt inactive
-			if !data.IsLoadTestActive && lastActiveLoadTestId != "" {
-				lt, _ := d.GetLoadTestByID(lastActiveLoadTestId)
-				if lt.Status == "running" {
-					d.UpdateLoadTest(lastActiveLoadTestId, bson.M{"status": "complete"})
-				}
-				// 	consolidate results and push to result collection
-				ltSummary, _ := d.FetchLoadTestResults(lastActiveLoadTestId)
-				d.CreateLoadTestSummary(ltSummary)
+	// every namespace has its own manager topic
+	namespaces := transport.ParseNamespaces(os.Getenv(NAMESPACES))
+	au := audit.NewAuditor(d)
+	hub := live.NewHub()
+
+	// queued load tests start once node groups are idle, which is checked
+	// at the heartbeat interval and whenever a load test ends
+	interval, staleAfter, unhealthyAfter := heartbeatConfig()
+	s := sched.NewScheduler(d, au, hub, m, namespaces, interval)
+	go s.Run(context.Background())
+
+	p := proc.NewProcessor(d, au, hub, m, s, namespaces)
+
+	handler := func(msg transport.Message) {
+		if err := p.Process(msg); err != nil {
+			logrus.Errorf("error while processing message on %s %v", msg.Topic, err.Error())
+			// keep the raw message around so it can be inspected and replayed
+			_, dlErr := d.CreateDeadLetter(&db.DeadLetter{
+				Topic:   msg.Topic,
+				Payload: string(proc.RedactPayload(msg.Payload)),
+				Error:   err.Error(),
+			})
+			if dlErr != nil {
+				logrus.Errorf("error while CreateDeadLetter %v", dlErr.Error())
 			}
-
 		}
+	}
+	for _, ns := range namespaces {
+		m.Sub(ns.Topic("manager"), handler)
+	}
 
-	})
+	a := auth.NewAuth(d, authSecret(), sessionTTL())
+	vi := view.NewView(d, m, p, a, au, hub, s, namespaces)
 
-	vi := view.NewView(d, m)
+	// mark node groups which stop sending heartbeats
+	monitor := health.NewMonitor(d, au, hub, m, interval, staleAfter, unhealthyAfter)
+	go monitor.Run(context.Background())
 
-	r := gin.New()
-	r.Use(
-		gin.LoggerWithWriter(gin.DefaultWriter, "/"),
-		gin.Recovery(),
-	)
+	apierr.UseJSONFieldNames()
+	r := setupRouter(vi, a)
 
-	r.GET("/", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "running",
-		})
-	})
+	// the spec is checked by the router test, drift is only worth a warning here
+	if err := openapi.CheckRoutes(r.Routes()); err != nil {
+		logrus.Warn(err.Error())
+	}
 
-	g := r.Group("/api")
+	r.Run() // listen and serve on 0.0.0.0:8080
+}
 
-	g.GET("/ngs", vi.ListNodeGroups)
-	g.GET("/ngs/:id", vi.GetNodeGroup)
-	g.PATCH("/ngs/:id", vi.UpdateLoadTest)
-	g.PUT("/ngs", vi.CreateNodeGroup)
-	g.DELETE("/ngs/:id", vi.DeleteNodeGroup)
+// newTransport - pick the message transport from the env, defaults to mqtt
+func newTransport() (transport.Transport, error) {
+	switch os.Getenv(TRANSPORT) {
+	case "", TRANSPORT_MQTT:
+		mqttHost := os.Getenv(MQTT_HOST)
+		mqttPort, _ := strconv.Atoi(os.Getenv(MQTT_PORT))
+		if os.Getenv(MQTT_EMBEDDED) == "true" {
+			// the embedded broker accepts every client without credentials so it
+			// only listens on loopback unless MQTT_LISTEN_HOST exposes it to
+			// remote node groups, the manager always uses loopback
+			if mqttPort == 0 {
+				mqttPort = 1883
+			}
+			listenHost := os.Getenv(MQTT_LISTEN_HOST)
+			if listenHost == "" {
+				listenHost = "127.0.0.1"
+			}
+			if _, err := mqttlib.NewBroker(listenHost, mqttPort); err != nil {
+				return nil, err
+			}
+			mqttHost = "127.0.0.1"
+			if listenHost != "127.0.0.1" && listenHost != "localhost" && listenHost != "::1" {
+				logrus.Warnf("embedded mqtt broker listens on %s without authentication, any client that can reach it can publish and subscribe", listenHost)
+			}
+			logrus.Infof("started embedded mqtt broker on %s", net.JoinHostPort(listenHost, strconv.Itoa(mqttPort)))
+		}
+		logrus.Infof("mqtt host %s port %v", mqttHost, mqttPort)
+
+		m := mqttlib.NewMqtt(mqttHost, mqttPort)
+		go m.Connect()
+		return m, nil
+	case TRANSPORT_NATS:
+		natsUrl := os.Getenv(NATS_URL)
+		if os.Getenv(NATS_EMBEDDED) == "true" {
+			natsPort, err := strconv.Atoi(os.Getenv(NATS_PORT))
+			if err != nil {
+				natsPort = 4222
+			}
+			ns, err := transport.NewEmbeddedNatsServer(os.Getenv(NATS_HOST), natsPort)
+			if err != nil {
+				return nil, err
+			}
+			natsUrl = ns.ClientURL()
+		}
+		logrus.Infof("nats url %s", natsUrl)
+		return transport.NewNats(natsUrl)
+	case TRANSPORT_INPROC:
+		logrus.Info("using in process transport")
+		return transport.NewInProc(), nil
+	default:
+		return nil, fmt.Errorf("unknown transport %s", os.Getenv(TRANSPORT))
+	}
+}
 
-	g.GET("/loadtests", vi.ListLoadTests)
-	g.GET("/loadtests/:id", vi.GetLoadTest)
-	g.PATCH("/loadtests/:id", vi.UpdateLoadTest)
-	g.PUT("/loadtests", vi.CreateLoadTest)
-	g.DELETE("/loadtests/:id", vi.DeleteLoadTest)
+// authSecret - key used to sign session tokens, a random key is used when none
+// is configured which invalidates all sessions on restart
+func authSecret() string {
+	secret := os.Getenv(AUTH_SECRET)
+	if secret != "" {
+		return secret
+	}
+	logrus.Warnf("%s not set, using a random secret", AUTH_SECRET)
+	key := make([]byte, 32)
+	rand.Read(key)
+	return hex.EncodeToString(key)
+}
 
-	g.PUT("/loadtests/stop", vi.StopLoadTest)
-	g.GET("/loadtests/:id/results", vi.GetLoadTestResults)
+func sessionTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv(SESSION_TTL))
+	if err != nil || ttl <= 0 {
+		return 24 * time.Hour
+	}
+	return ttl
+}
 
-	r.Run() // listen and serve on 0.0.0.0:8080
+// heartbeatConfig - expected heartbeat interval of node groups and after how
+// many missed heartbeats they become stale and unhealthy
+func heartbeatConfig() (time.Duration, int, int) {
+	interval, err := time.ParseDuration(os.Getenv(HB_INTERVAL))
+	if err != nil || interval <= 0 {
+		interval = 10 * time.Second
+	}
+	staleAfter, err := strconv.Atoi(os.Getenv(HB_STALE_AFTER))
+	if err != nil || staleAfter <= 0 {
+		staleAfter = 2
+	}
+	unhealthyAfter, err := strconv.Atoi(os.Getenv(HB_UNHEALTHY))
+	if err != nil || unhealthyAfter <= staleAfter {
+		unhealthyAfter = staleAfter + 3
+	}
+	return interval, staleAfter, unhealthyAfter
 }
@@ -0,0 +1,33 @@
+package api
+
+// error codes of the api
+const (
+	CodeBadRequest    = "bad_request"
+	CodeUnauthorized  = "unauthorized"
+	CodeForbidden     = "forbidden"
+	CodeNotFound      = "not_found"
+	CodeConflict      = "conflict"
+	CodeValidation    = "validation_failed"
+	CodeInternal      = "internal_error"
+	CodeUnprocessable = "unprocessable"
+	CodeQuotaExceeded = "quota_exceeded"
+	CodeAlreadyExists = "already_exists"
+	CodeNoCapacity    = "insufficient_capacity"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error - error returned by every endpoint as {"error": {...}}
+type Error struct {
+	Status      int          `json:"-"`
+	Code        string       `json:"code"`
+	Message     string       `json:"message"`
+	FieldErrors []FieldError `json:"field_errors,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
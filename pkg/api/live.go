@@ -0,0 +1,58 @@
+package api
+
+import "time"
+
+// events of the live stream of a load test
+const (
+	EventStats   = "stats"
+	EventStatus  = "status"
+	EventSummary = "summary"
+	EventHealth  = "nodegroup_health"
+)
+
+type Event struct {
+	Type       string `json:"type"`
+	LoadTestID string `json:"load_test_id"`
+	Data       any    `json:"data"`
+}
+
+type StatusChange struct {
+	Status string `json:"status"`
+}
+
+type HealthChange struct {
+	NodeGroupID  string `json:"node_group_id"`
+	HealthStatus string `json:"health_status"`
+}
+
+// IsFinal - no more events follow a summary
+func (e Event) IsFinal() bool {
+	return e.Type == EventSummary
+}
+
+type NodeGroupStatus struct {
+	IsHealthy     bool      `json:"is_healthy"`
+	Nodes         int       `json:"nodes"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+}
+
+type Percentiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// Stats - live view of a running load test
+type Stats struct {
+	LoadTestID    string                     `json:"load_test_id"`
+	TotalRequests int64                      `json:"total_requests"`
+	SuccessCount  int64                      `json:"success_count"`
+	FailureCount  int64                      `json:"failure_count"`
+	CurrentTPS    float64                    `json:"current_tps"`
+	ErrorRate     float64                    `json:"error_rate"`
+	LatencyMs     Percentiles                `json:"latency_ms"`
+	NodeGroups    map[string]NodeGroupStatus `json:"node_groups"`
+	UpdatedAt     time.Time                  `json:"updated_at"`
+}
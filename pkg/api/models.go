@@ -0,0 +1,459 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// models, requests and responses of the api shared by the manager and its
+// clients, without dependencies so that clients don't pull in the database,
+// transports or http server of the manager
+
+const (
+	APIKeyHeader  = "X-API-Key"
+	ProjectHeader = "X-Project-ID"
+)
+
+type Data map[string]any
+type NodeUpdates map[string][]Data
+
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// roleLevels - every role has the permissions of the roles below it
+var roleLevels = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+func IsValidRole(role string) bool {
+	_, ok := roleLevels[role]
+	return ok
+}
+
+type User struct {
+	ID           string    `bson:"_id" json:"_id"`
+	Name         string    `bson:"name" json:"name"`
+	Email        string    `bson:"email" json:"email"`
+	PasswordHash string    `bson:"password_hash" json:"-"`
+	Role         string    `bson:"role" json:"role"`
+	CreatedAt    time.Time `bson:"created_at" json:"created_at"`
+}
+
+// HasRole - whether the user has at least the permissions of role
+func (u User) HasRole(role string) bool {
+	return roleLevels[u.Role] >= roleLevels[role] && roleLevels[role] > 0
+}
+
+type APIKey struct {
+	ID         string    `bson:"_id" json:"_id"`
+	Name       string    `bson:"name" json:"name"`
+	UserID     string    `bson:"user_id" json:"user_id"`
+	Prefix     string    `bson:"prefix" json:"prefix"`
+	KeyHash    string    `bson:"key_hash" json:"-"`
+	Scope      string    `bson:"scope" json:"scope"`
+	ExpiresAt  time.Time `bson:"expires_at" json:"expires_at"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+	LastUsedAt time.Time `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	RevokedAt  time.Time `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+// IsActive - key can be used to authenticate
+func (k APIKey) IsActive() bool {
+	return k.RevokedAt.IsZero() && k.ExpiresAt.After(time.Now())
+}
+
+// EnrollmentToken - lets node groups register themselves into a project
+type EnrollmentToken struct {
+	ID          string    `bson:"_id" json:"_id"`
+	Name        string    `bson:"name" json:"name"`
+	Prefix      string    `bson:"prefix" json:"prefix"`
+	TokenHash   string    `bson:"token_hash" json:"-"`
+	ProjectID   string    `bson:"project_id" json:"project_id"`
+	Namespace   string    `bson:"namespace" json:"namespace"`
+	Shared      bool      `bson:"shared" json:"shared"`
+	AutoApprove bool      `bson:"auto_approve" json:"auto_approve"`
+	UseCount    int       `bson:"use_count" json:"use_count"`
+	CreatedBy   string    `bson:"created_by" json:"created_by"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	ExpiresAt   time.Time `bson:"expires_at" json:"expires_at"`
+	RevokedAt   time.Time `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+}
+
+func (t EnrollmentToken) IsActive() bool {
+	return t.RevokedAt.IsZero() && t.ExpiresAt.After(time.Now())
+}
+
+type Session struct {
+	ID        string    `bson:"_id" json:"_id"`
+	UserID    string    `bson:"user_id" json:"user_id"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+}
+
+type LoadTest struct {
+	ID          string    `bson:"_id" json:"_id,omitempty"`
+	Description string    `bson:"description" json:"description"`
+	TPS         float64   `bson:"tps" json:"tps"`
+	Duration    int       `bson:"duration" json:"duration"`
+	Logic       string    `bson:"logic" json:"logic"`
+	CreatedBy   string    `bson:"created_by" json:"created_by"`
+	StartTime   time.Time `bson:"start_time" json:"start_time"`
+	EndTime     time.Time `bson:"end_time" json:"end_time"`
+	Status      string    `bson:"status" json:"status"`
+	Namespace   string    `bson:"namespace" json:"namespace"`
+	ProjectID   string    `bson:"project_id" json:"project_id"`
+
+	// queued load tests are started by priority, highest first, and then in
+	// the order they were queued
+	Priority int       `bson:"priority" json:"priority"`
+	QueuedAt time.Time `bson:"queued_at,omitempty" json:"queued_at,omitempty"`
+
+	// NodeGroups - the node groups the load test is bound to, requested ones
+	// while queued and the ones it started on once running. Load tests started
+	// before node groups were bound have none and ran on all of them
+	NodeGroups []string `bson:"node_groups,omitempty" json:"node_groups,omitempty"`
+
+	// AcknowledgedAt - first heartbeat of a node group running the load test,
+	// load tests which no node group picks up in time fail
+	AcknowledgedAt time.Time `bson:"acknowledged_at,omitempty" json:"acknowledged_at,omitempty"`
+
+	// StartedNodeGroups - node groups which reported running the load test,
+	// DoneNodeGroups the ones which stopped running it or were lost
+	StartedNodeGroups []string `bson:"started_node_groups,omitempty" json:"started_node_groups,omitempty"`
+	DoneNodeGroups    []string `bson:"done_node_groups,omitempty" json:"done_node_groups,omitempty"`
+}
+
+// RunsOn - whether the node group takes part in the load test
+func (lt LoadTest) RunsOn(ngId string) bool {
+	return len(lt.NodeGroups) == 0 || slices.Contains(lt.NodeGroups, ngId)
+}
+
+// IsRunning - node groups run the load test, a stopping load test runs until
+// its node groups stopped it
+func (lt LoadTest) IsRunning() bool {
+	return lt.Status == "running" || lt.Status == "stopping"
+}
+
+// EndStatus - status a running load test ends with, a stopping load test is
+// stopped however it ends
+func (lt LoadTest) EndStatus(status string) string {
+	if lt.Status == "stopping" {
+		return "stopped"
+	}
+	return status
+}
+
+// IsDone - all node groups of the load test are done with it, load tests
+// without bound node groups wait for the ones which reported running them
+func (lt LoadTest) IsDone() bool {
+	ngIds := lt.NodeGroups
+	if len(ngIds) == 0 {
+		ngIds = lt.StartedNodeGroups
+	}
+	if len(ngIds) == 0 {
+		return false
+	}
+	for _, ngId := range ngIds {
+		if !slices.Contains(lt.DoneNodeGroups, ngId) {
+			return false
+		}
+	}
+	return true
+}
+
+// health of a node group, heartbeats make it healthy or unhealthy and the
+// health monitor makes it stale and then unhealthy when heartbeats stop
+const (
+	HealthHealthy   = "healthy"
+	HealthStale     = "stale"
+	HealthUnhealthy = "unhealthy"
+)
+
+type NodeGroup struct {
+	ID              string            `bson:"_id" json:"_id"`
+	Nodes           []string          `bson:"nodes"`
+	Topic           string            `bson:"topic"`
+	Namespace       string            `bson:"namespace"`
+	ProjectID       string            `bson:"project_id"`
+	Shared          bool              `bson:"shared"`
+	IsHealthy       bool              `bson:"is_healthy"`
+	HealthStatus    string            `bson:"health_status"`
+	LastHealthCheck time.Time         `bson:"last_health_time"`
+	Pending         bool              `bson:"pending"`
+	Capacity        NodeGroupCapacity `bson:"capacity"`
+
+	// cordoned node groups get no new load tests, draining ones are still
+	// stopping the load tests they ran when they were drained
+	Cordoned bool `bson:"cordoned"`
+	Draining bool `bson:"draining"`
+
+	// MultiTenant - the node group declared it can run several load tests at
+	// once, others serve one load test at a time
+	MultiTenant bool `bson:"multi_tenant"`
+
+	// Config - desired config pushed to the node group, which reports the
+	// version it applied with its heartbeats
+	Config               NodeGroupConfig `bson:"config"`
+	AppliedConfigVersion int64           `bson:"applied_config_version"`
+}
+
+// NodeGroupConfig - settings of a node group, zero values leave the node group
+// default in place. Version 0 means no config was ever set
+type NodeGroupConfig struct {
+	Version           int64     `bson:"version" json:"version"`
+	HeartbeatInterval int       `bson:"heartbeat_interval" json:"heartbeat_interval"`
+	ResultBatchSize   int       `bson:"result_batch_size" json:"result_batch_size"`
+	LogLevel          string    `bson:"log_level" json:"log_level"`
+	MaxConcurrency    int       `bson:"max_concurrency" json:"max_concurrency"`
+	UpdatedBy         string    `bson:"updated_by" json:"updated_by"`
+	UpdatedAt         time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// ConfigInSync - the node group applied its desired config
+func (ng NodeGroup) ConfigInSync() bool {
+	return ng.AppliedConfigVersion == ng.Config.Version
+}
+
+// NodeGroupCapacity - what a node group reports it can generate, zero MaxTPS
+// when the node group never reported its capacity
+type NodeGroupCapacity struct {
+	MaxTPS         float64   `bson:"max_tps" json:"max_tps"`
+	Concurrency    int       `bson:"concurrency" json:"concurrency"`
+	CPUHeadroom    float64   `bson:"cpu_headroom" json:"cpu_headroom"`
+	MemoryHeadroom float64   `bson:"memory_headroom" json:"memory_headroom"`
+	ReportedAt     time.Time `bson:"reported_at,omitempty" json:"reported_at,omitempty"`
+}
+
+func (c NodeGroupCapacity) IsKnown() bool {
+	return c.MaxTPS > 0
+}
+
+// Health - health status of the node group, node groups which were last
+// updated before the health status existed only have is_healthy
+func (ng NodeGroup) Health() string {
+	if ng.HealthStatus != "" {
+		return ng.HealthStatus
+	}
+	if ng.IsHealthy {
+		return HealthHealthy
+	}
+	return HealthUnhealthy
+}
+
+// HealthEvent - the health status of a node group changed
+type HealthEvent struct {
+	ID          string    `bson:"_id" json:"_id"`
+	NodeGroupID string    `bson:"node_group_id" json:"node_group_id"`
+	From        string    `bson:"from" json:"from"`
+	To          string    `bson:"to" json:"to"`
+	Timestamp   time.Time `bson:"timestamp" json:"timestamp"`
+}
+
+type NGHeartbeat struct {
+	Action           string   `json:"action"`
+	NodeGroupStatus  string   `json:"ng_status"`
+	NodeGroupID      string   `json:"ng_id"`
+	Nodes            []string `json:"nodes"`
+	IsLoadTestActive bool     `json:"isLoadTestActive"`
+	Timestamp        string   `json:"timestamp"`
+	NodeUpdates      string   `json:"node_updates,omitempty"`
+	LoadTestId       string   `json:"load_test_id,omitempty"`
+	EnrollmentToken  string   `json:"enrollment_token,omitempty"`
+	ReplyTo          string   `json:"reply_to,omitempty"`
+
+	// Capacity - optional, kept from the last heartbeat which had it
+	Capacity *NodeGroupCapacity `json:"capacity,omitempty"`
+
+	// ConfigVersion - version of the config pushed with configure which the
+	// node group applied, 0 when it runs on its defaults
+	ConfigVersion int64 `json:"config_version,omitempty"`
+
+	// MultiTenant node groups list every load test they run in
+	// ActiveLoadTests, others only give LoadTestId
+	MultiTenant     bool     `json:"multi_tenant,omitempty"`
+	ActiveLoadTests []string `json:"active_load_tests,omitempty"`
+}
+
+// RunningLoadTests - the load tests the node group runs according to the
+// heartbeat
+func (h NGHeartbeat) RunningLoadTests() []string {
+	if len(h.ActiveLoadTests) > 0 {
+		return h.ActiveLoadTests
+	}
+	if h.IsLoadTestActive && h.LoadTestId != "" {
+		return []string{h.LoadTestId}
+	}
+	return []string{}
+}
+
+type NodeHeartBeat struct {
+	Action          string `json:"action"`
+	IsTestActive    string `json:"isTestActive"`
+	LoadTestID      string `json:"load_test_id"`
+	LoadTestResults string `json:"load_test_results"`
+	NodeID          string `json:"node_id"`
+	NodeStatus      string `json:"node_status"`
+	Timestamp       string `json:"timestamp"`
+	Sequence        int64  `json:"seq,omitempty"`
+	Version         string `json:"version,omitempty"`
+	Capacity        int    `json:"capacity,omitempty"`
+}
+
+// BatchKey - identifies the result batch carried by a node heartbeat so that
+// redelivered batches can be recognised, empty when it can't be identified
+func (n NodeHeartBeat) BatchKey() string {
+	if n.NodeID == "" {
+		return ""
+	}
+	if n.Sequence != 0 {
+		return fmt.Sprintf("%s/seq/%d", n.NodeID, n.Sequence)
+	}
+	if n.Timestamp != "" {
+		return fmt.Sprintf("%s/ts/%s", n.NodeID, n.Timestamp)
+	}
+	return ""
+}
+
+const (
+	NodeEventJoined = "joined"
+	NodeEventLeft   = "left"
+	NodeEventStatus = "status_change"
+
+	// NodeStatusLeft - status of nodes no longer listed by their node group
+	NodeStatusLeft = "left"
+)
+
+// Node - a node of a node group as last reported by its heartbeats, nodes
+// which left their node group are kept with left_at set
+type Node struct {
+	ID          string    `bson:"_id" json:"_id"`
+	NodeID      string    `bson:"node_id" json:"node_id"`
+	NodeGroupID string    `bson:"node_group_id" json:"node_group_id"`
+	Status      string    `bson:"status" json:"status"`
+	Version     string    `bson:"version" json:"version"`
+	Capacity    int       `bson:"capacity" json:"capacity"`
+	LoadTestID  string    `bson:"load_test_id" json:"load_test_id"`
+	LastSeen    time.Time `bson:"last_seen" json:"last_seen"`
+	JoinedAt    time.Time `bson:"joined_at" json:"joined_at"`
+	LeftAt      time.Time `bson:"left_at,omitempty" json:"left_at,omitempty"`
+}
+
+func (n Node) HasLeft() bool {
+	return !n.LeftAt.IsZero()
+}
+
+// NodeEvent - a node joined or left its node group or changed status
+type NodeEvent struct {
+	ID          string    `bson:"_id" json:"_id"`
+	NodeID      string    `bson:"node_id" json:"node_id"`
+	NodeGroupID string    `bson:"node_group_id" json:"node_group_id"`
+	Event       string    `bson:"event" json:"event"`
+	Status      string    `bson:"status" json:"status"`
+	Timestamp   time.Time `bson:"timestamp" json:"timestamp"`
+}
+
+type LoadTestEntry struct {
+	IsSuccess  string `bson:"isSuccess"`
+	LatencyMs  string `bson:"latencyMs"`
+	Response   string `bson:"response"`
+	StatusCode string `bson:"statusCode"`
+}
+
+type DeadLetter struct {
+	ID           string    `bson:"_id" json:"_id"`
+	Topic        string    `bson:"topic" json:"topic"`
+	Payload      string    `bson:"payload" json:"payload"`
+	Error        string    `bson:"error" json:"error"`
+	ReceivedAt   time.Time `bson:"received_at" json:"received_at"`
+	ReplayCount  int       `bson:"replay_count" json:"replay_count"`
+	LastReplayAt time.Time `bson:"last_replay_at,omitempty" json:"last_replay_at,omitempty"`
+}
+
+const (
+	ActorTypeUser   = "user"
+	ActorTypeAPIKey = "api_key"
+	ActorTypeSystem = "system"
+)
+
+type Change struct {
+	Before any `bson:"before" json:"before"`
+	After  any `bson:"after" json:"after"`
+}
+
+type AuditEntry struct {
+	ID         string            `bson:"_id" json:"_id"`
+	Actor      string            `bson:"actor" json:"actor"`
+	ActorType  string            `bson:"actor_type" json:"actor_type"`
+	APIKeyID   string            `bson:"api_key_id,omitempty" json:"api_key_id,omitempty"`
+	Action     string            `bson:"action" json:"action"`
+	TargetType string            `bson:"target_type" json:"target_type"`
+	TargetID   string            `bson:"target_id" json:"target_id"`
+	Changes    map[string]Change `bson:"changes,omitempty" json:"changes,omitempty"`
+	Timestamp  time.Time         `bson:"timestamp" json:"timestamp"`
+}
+
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   string
+	From       time.Time
+	To         time.Time
+	Limit      int64
+}
+
+type ProjectQuota struct {
+	MaxConcurrentTests int     `bson:"max_concurrent_tests" json:"max_concurrent_tests"`
+	MaxTPS             float64 `bson:"max_tps" json:"max_tps"`
+}
+
+type Project struct {
+	ID          string       `bson:"_id" json:"_id"`
+	Name        string       `bson:"name" json:"name"`
+	Description string       `bson:"description" json:"description"`
+	Members     []string     `bson:"members" json:"members"`
+	Quota       ProjectQuota `bson:"quota" json:"quota"`
+	CreatedBy   string       `bson:"created_by" json:"created_by"`
+	CreatedAt   time.Time    `bson:"created_at" json:"created_at"`
+}
+
+func (p Project) IsMember(userId string) bool {
+	for _, member := range p.Members {
+		if member == userId {
+			return true
+		}
+	}
+	return false
+}
+
+type LoadTestFilter struct {
+	ProjectID string
+	Status    string
+	CreatedBy string
+	From      time.Time
+	To        time.Time
+	Text      string
+}
+
+type NodeGroupFilter struct {
+	ProjectID    string
+	Namespace    *string
+	IsHealthy    *bool
+	HealthStatus string
+	Pending      *bool
+	Cordoned     *bool
+	ConfigDrift  *bool
+}
+
+type Page[T any] struct {
+	Items  []T   `json:"items"`
+	Total  int64 `json:"total"`
+	Offset int64 `json:"offset"`
+	Limit  int64 `json:"limit"`
+}
@@ -0,0 +1,68 @@
+package api
+
+import "testing"
+
+func TestNodeHeartBeatBatchKey(t *testing.T) {
+	tests := []struct {
+		name string
+		hb   NodeHeartBeat
+		want string
+	}{
+		{"sequence", NodeHeartBeat{NodeID: "n1", Sequence: 7, Timestamp: "1700000000"}, "n1/seq/7"},
+		{"timestamp", NodeHeartBeat{NodeID: "n1", Timestamp: "1700000000"}, "n1/ts/1700000000"},
+		{"no node", NodeHeartBeat{Sequence: 7, Timestamp: "1700000000"}, ""},
+		{"nothing to identify the batch", NodeHeartBeat{NodeID: "n1"}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.hb.BatchKey(); got != tt.want {
+			t.Errorf("%s: BatchKey() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadTestIsDone(t *testing.T) {
+	tests := []struct {
+		name string
+		lt   LoadTest
+		want bool
+	}{
+		{"bound, none done", LoadTest{NodeGroups: []string{"a", "b"}}, false},
+		{"bound, one done", LoadTest{NodeGroups: []string{"a", "b"}, DoneNodeGroups: []string{"a"}}, false},
+		{"bound, all done", LoadTest{NodeGroups: []string{"a", "b"}, DoneNodeGroups: []string{"b", "a"}}, true},
+		{"bound, started ones don't matter", LoadTest{NodeGroups: []string{"a", "b"}, StartedNodeGroups: []string{"a"}, DoneNodeGroups: []string{"a"}}, false},
+		{"unbound, never started", LoadTest{DoneNodeGroups: []string{"a"}}, false},
+		{"unbound, started ones done", LoadTest{StartedNodeGroups: []string{"a"}, DoneNodeGroups: []string{"a", "b"}}, true},
+		{"unbound, started one running", LoadTest{StartedNodeGroups: []string{"a", "b"}, DoneNodeGroups: []string{"a"}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.lt.IsDone(); got != tt.want {
+			t.Errorf("%s: IsDone() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadTestEndStatus(t *testing.T) {
+	tests := []struct {
+		status    string
+		isRunning bool
+		complete  string
+		failed    string
+	}{
+		{"running", true, "complete", "failed"},
+		{"stopping", true, "stopped", "stopped"},
+		{"queued", false, "complete", "failed"},
+		{"stopped", false, "complete", "failed"},
+	}
+	for _, tt := range tests {
+		lt := LoadTest{Status: tt.status}
+		if lt.IsRunning() != tt.isRunning {
+			t.Errorf("%s: IsRunning() = %v", tt.status, lt.IsRunning())
+		}
+		if got := lt.EndStatus("complete"); got != tt.complete {
+			t.Errorf("%s: EndStatus(complete) = %s, want %s", tt.status, got, tt.complete)
+		}
+		if got := lt.EndStatus("failed"); got != tt.failed {
+			t.Errorf("%s: EndStatus(failed) = %s, want %s", tt.status, got, tt.failed)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package api
+
+import "time"
+
+// request bodies of the api, the manager validates them with the binding tags
+
+type CreateNodeGroupRequest struct {
+	Topic     string   `json:"topic" binding:"required"`
+	Namespace string   `json:"namespace"`
+	Nodes     []string `json:"nodes"`
+	Shared    bool     `json:"shared"`
+}
+
+func (r CreateNodeGroupRequest) NodeGroup() NodeGroup {
+	return NodeGroup{
+		Topic:     r.Topic,
+		Namespace: r.Namespace,
+		Nodes:     r.Nodes,
+		Shared:    r.Shared,
+	}
+}
+
+type UpdateNodeGroupRequest struct {
+	Topic     *string   `json:"topic" binding:"omitempty,min=1"`
+	Namespace *string   `json:"namespace"`
+	Nodes     *[]string `json:"nodes"`
+	Shared    *bool     `json:"shared"`
+}
+
+func (r UpdateNodeGroupRequest) Update() map[string]any {
+	update := map[string]any{}
+	if r.Topic != nil {
+		update["topic"] = *r.Topic
+	}
+	if r.Namespace != nil {
+		update["namespace"] = *r.Namespace
+	}
+	if r.Nodes != nil {
+		update["nodes"] = *r.Nodes
+	}
+	if r.Shared != nil {
+		update["shared"] = *r.Shared
+	}
+	return update
+}
+
+type CreateLoadTestRequest struct {
+	Description string   `json:"description" binding:"max=1000"`
+	TPS         float64  `json:"tps" binding:"required,gt=0"`
+	Duration    int      `json:"duration" binding:"required,gt=0"`
+	Logic       string   `json:"logic" binding:"required"`
+	Namespace   string   `json:"namespace"`
+	Priority    int      `json:"priority"`
+	NodeGroups  []string `json:"node_groups" binding:"omitempty,unique"`
+}
+
+func (r CreateLoadTestRequest) LoadTest() LoadTest {
+	return LoadTest{
+		Description: r.Description,
+		TPS:         r.TPS,
+		Duration:    r.Duration,
+		Logic:       r.Logic,
+		Namespace:   r.Namespace,
+		Priority:    r.Priority,
+		NodeGroups:  r.NodeGroups,
+	}
+}
+
+type ReorderQueuedLoadTestRequest struct {
+	Priority int `json:"priority"`
+}
+
+type UpdateLoadTestRequest struct {
+	Description *string    `json:"description" binding:"omitempty,max=1000"`
+	TPS         *float64   `json:"tps" binding:"omitempty,gt=0"`
+	Duration    *int       `json:"duration" binding:"omitempty,gt=0"`
+	Logic       *string    `json:"logic" binding:"omitempty,min=1"`
+	EndTime     *time.Time `json:"end_time"`
+	CreatedBy   *string    `json:"created_by"`
+}
+
+func (r UpdateLoadTestRequest) Update() map[string]any {
+	update := map[string]any{}
+	if r.Description != nil {
+		update["description"] = *r.Description
+	}
+	if r.TPS != nil {
+		update["tps"] = *r.TPS
+	}
+	if r.Duration != nil {
+		update["duration"] = *r.Duration
+	}
+	if r.Logic != nil {
+		update["logic"] = *r.Logic
+	}
+	if r.EndTime != nil {
+		update["end_time"] = *r.EndTime
+	}
+	if r.CreatedBy != nil {
+		update["created_by"] = *r.CreatedBy
+	}
+	return update
+}
+
+type RegisterRequest struct {
+	Name     string `json:"name" binding:"max=200"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=viewer operator admin"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string    `json:"name" binding:"max=200"`
+	Scope     string    `json:"scope" binding:"required,oneof=viewer operator admin"`
+	ExpiresAt time.Time `json:"expires_at" binding:"required"`
+}
+
+type SetNodeGroupConfigRequest struct {
+	HeartbeatInterval int    `json:"heartbeat_interval" binding:"gte=0,lte=3600"`
+	ResultBatchSize   int    `json:"result_batch_size" binding:"gte=0"`
+	LogLevel          string `json:"log_level" binding:"omitempty,oneof=debug info warn error"`
+	MaxConcurrency    int    `json:"max_concurrency" binding:"gte=0"`
+}
+
+func (r SetNodeGroupConfigRequest) Config() NodeGroupConfig {
+	return NodeGroupConfig{
+		HeartbeatInterval: r.HeartbeatInterval,
+		ResultBatchSize:   r.ResultBatchSize,
+		LogLevel:          r.LogLevel,
+		MaxConcurrency:    r.MaxConcurrency,
+	}
+}
+
+type CreateEnrollmentTokenRequest struct {
+	Name        string    `json:"name" binding:"max=200"`
+	ProjectID   string    `json:"project_id" binding:"required"`
+	Namespace   string    `json:"namespace"`
+	Shared      bool      `json:"shared"`
+	AutoApprove bool      `json:"auto_approve"`
+	ExpiresAt   time.Time `json:"expires_at" binding:"required"`
+}
+
+type ProjectQuotaRequest struct {
+	MaxConcurrentTests int     `json:"max_concurrent_tests" binding:"gte=0"`
+	MaxTPS             float64 `json:"max_tps" binding:"gte=0"`
+}
+
+type CreateProjectRequest struct {
+	Name        string              `json:"name" binding:"required,max=200"`
+	Description string              `json:"description" binding:"max=1000"`
+	Quota       ProjectQuotaRequest `json:"quota"`
+}
+
+func (r CreateProjectRequest) Project() Project {
+	return Project{
+		Name:        r.Name,
+		Description: r.Description,
+		Quota: ProjectQuota{
+			MaxConcurrentTests: r.Quota.MaxConcurrentTests,
+			MaxTPS:             r.Quota.MaxTPS,
+		},
+	}
+}
+
+type UpdateProjectRequest struct {
+	Name        *string              `json:"name" binding:"omitempty,min=1,max=200"`
+	Description *string              `json:"description" binding:"omitempty,max=1000"`
+	Quota       *ProjectQuotaRequest `json:"quota"`
+}
+
+func (r UpdateProjectRequest) Update() map[string]any {
+	update := map[string]any{}
+	if r.Name != nil {
+		update["name"] = *r.Name
+	}
+	if r.Description != nil {
+		update["description"] = *r.Description
+	}
+	if r.Quota != nil {
+		update["quota"] = ProjectQuota{
+			MaxConcurrentTests: r.Quota.MaxConcurrentTests,
+			MaxTPS:             r.Quota.MaxTPS,
+		}
+	}
+	return update
+}
+
+type AddProjectMemberRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
@@ -0,0 +1,39 @@
+package api
+
+import "time"
+
+// response bodies of the api which aren't models
+
+// NodeGroupConfigStatus - desired config of a node group and the version it
+// reported to run, the node group drifted while they differ
+type NodeGroupConfigStatus struct {
+	NodeGroupID    string          `json:"node_group_id"`
+	Desired        NodeGroupConfig `json:"desired"`
+	AppliedVersion int64           `json:"applied_version"`
+	InSync         bool            `json:"in_sync"`
+}
+
+// HealthPeriod - a node group kept the same health status from start to end,
+// the load tests which overlapped are given for stale and unhealthy periods
+type HealthPeriod struct {
+	Status    string    `json:"status"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	LoadTests []string  `json:"load_tests,omitempty"`
+}
+
+// NodeGroupHealthReport - uptime is the share of the time with a known health
+// status the node group was healthy, nil when it never was known in the range
+type NodeGroupHealthReport struct {
+	NodeGroupID   string         `json:"node_group_id"`
+	From          time.Time      `json:"from"`
+	To            time.Time      `json:"to"`
+	UptimePercent *float64       `json:"uptime_percent,omitempty"`
+	Timeline      []HealthPeriod `json:"timeline"`
+}
+
+type NodeGroupNodes struct {
+	NodeGroupID string      `json:"node_group_id"`
+	Nodes       []Node      `json:"nodes"`
+	History     []NodeEvent `json:"history"`
+}
@@ -0,0 +1,191 @@
+package apierr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// error codes of the api
+const (
+	CodeBadRequest    = api.CodeBadRequest
+	CodeUnauthorized  = api.CodeUnauthorized
+	CodeForbidden     = api.CodeForbidden
+	CodeNotFound      = api.CodeNotFound
+	CodeConflict      = api.CodeConflict
+	CodeValidation    = api.CodeValidation
+	CodeInternal      = api.CodeInternal
+	CodeUnprocessable = api.CodeUnprocessable
+	CodeQuotaExceeded = api.CodeQuotaExceeded
+	CodeAlreadyExists = api.CodeAlreadyExists
+	CodeNoCapacity    = api.CodeNoCapacity
+)
+
+// the error type is defined in pkg/api so that api clients can use it
+type (
+	FieldError = api.FieldError
+	Error      = api.Error
+)
+
+func New(status int, code string, format string, args ...any) *Error {
+	return &Error{
+		Status:  status,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func BadRequest(format string, args ...any) *Error {
+	return New(400, CodeBadRequest, format, args...)
+}
+
+func Unauthorized(format string, args ...any) *Error {
+	return New(401, CodeUnauthorized, format, args...)
+}
+
+func Forbidden(format string, args ...any) *Error {
+	return New(403, CodeForbidden, format, args...)
+}
+
+func NotFound(format string, args ...any) *Error {
+	return New(404, CodeNotFound, format, args...)
+}
+
+func Conflict(format string, args ...any) *Error {
+	return New(409, CodeConflict, format, args...)
+}
+
+func Unprocessable(format string, args ...any) *Error {
+	return New(422, CodeUnprocessable, format, args...)
+}
+
+// Invalid - validation error of a single request field
+func Invalid(field string, format string, args ...any) *Error {
+	message := fmt.Sprintf(format, args...)
+	return &Error{
+		Status:      422,
+		Code:        CodeValidation,
+		Message:     "validation failed",
+		FieldErrors: []FieldError{{Field: field, Message: message}},
+	}
+}
+
+// From - map any error to the api error model, unknown errors are internal
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		result := &Error{
+			Status:  422,
+			Code:    CodeValidation,
+			Message: "validation failed",
+		}
+		for _, fe := range validationErrs {
+			result.FieldErrors = append(result.FieldErrors, FieldError{
+				Field:   fieldName(fe),
+				Message: fieldMessage(fe),
+			})
+		}
+		return result
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return NotFound("not found")
+	case errors.Is(err, db.ErrInvalidArgument):
+		return BadRequest(err.Error())
+	case mongo.IsDuplicateKeyError(err):
+		return New(409, CodeAlreadyExists, "already exists")
+	case errors.Is(err, io.EOF):
+		return BadRequest("request body is required")
+	case errors.As(err, &syntaxErr):
+		return BadRequest("invalid json %s", err.Error())
+	case errors.As(err, &typeErr):
+		return &Error{
+			Status:      422,
+			Code:        CodeValidation,
+			Message:     "validation failed",
+			FieldErrors: []FieldError{{Field: typeErr.Field, Message: "must be a " + typeErr.Type.String()}},
+		}
+	}
+
+	return New(500, CodeInternal, err.Error())
+}
+
+// Respond - write err in the error model
+func Respond(c *gin.Context, err error) {
+	apiErr := From(err)
+	if apiErr.Status >= 500 {
+		logrus.Errorf("error while handling %s %s %v", c.Request.Method, c.FullPath(), err.Error())
+	}
+	c.JSON(apiErr.Status, gin.H{"error": apiErr})
+}
+
+// Abort - write err in the error model and stop the handler chain
+func Abort(c *gin.Context, err error) {
+	apiErr := From(err)
+	c.AbortWithStatusJSON(apiErr.Status, gin.H{"error": apiErr})
+}
+
+// UseJSONFieldNames - report validation errors with the json names of fields
+func UseJSONFieldNames() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// fieldName - path of the field without the name of the request struct
+func fieldName(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return fe.Field()
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	default:
+		return "failed the " + fe.Tag() + " check"
+	}
+}
@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"github.com/sirupsen/logrus"
+)
+
+// actions recorded in the audit log
+const (
+	Create     = "create"
+	Update     = "update"
+	Delete     = "delete"
+	Start      = "start"
+	Stop       = "stop"
+	Complete   = "complete"
+	Fail       = "fail"
+	Health     = "health_change"
+	Replay     = "replay"
+	Purge      = "purge"
+	Register   = "register"
+	Login      = "login"
+	Logout     = "logout"
+	Revoke     = "revoke"
+	RoleChange = "role_change"
+	Approve    = "approve"
+	Cordon     = "cordon"
+	Uncordon   = "uncordon"
+	Drain      = "drain"
+	Configure  = "configure"
+	Cancel     = "cancel"
+)
+
+// targets of audited actions
+const (
+	LoadTest   = "loadtest"
+	NodeGroup  = "nodegroup"
+	DeadLetter = "deadletter"
+	User       = "user"
+	APIKey     = "apikey"
+	Project    = "project"
+	Enrollment = "enrollmenttoken"
+)
+
+type Auditor struct {
+	d *db.DB
+}
+
+func NewAuditor(database *db.DB) *Auditor {
+	return &Auditor{
+		d: database,
+	}
+}
+
+// Record - store the entry with the fields that differ between before and
+// after, either can be nil for created or deleted targets. Failures are only
+// logged so that auditing never fails the audited action
+func (a *Auditor) Record(entry db.AuditEntry, before any, after any) {
+	entry.Changes = Diff(before, after)
+	if _, err := a.d.CreateAuditEntry(&entry); err != nil {
+		logrus.Errorf("error while CreateAuditEntry %v", err.Error())
+	}
+}
+
+// System - record an automatic transition made by the manager itself
+func (a *Auditor) System(action string, targetType string, targetId string, before any, after any) {
+	a.Record(db.AuditEntry{
+		Actor:      db.ActorTypeSystem,
+		ActorType:  db.ActorTypeSystem,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetId,
+	}, before, after)
+}
+
+// Diff - top level fields which changed between the json forms of before and after
+func Diff(before any, after any) map[string]db.Change {
+	b, a := toMap(before), toMap(after)
+	changes := map[string]db.Change{}
+	for k, v := range b {
+		if !reflect.DeepEqual(v, a[k]) {
+			changes[k] = db.Change{Before: v, After: a[k]}
+		}
+	}
+	for k, v := range a {
+		if _, ok := b[k]; !ok {
+			changes[k] = db.Change{Before: nil, After: v}
+		}
+	}
+	return changes
+}
+
+func toMap(v any) map[string]any {
+	result := map[string]any{}
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil() {
+		return result
+	}
+	bytes, _ := json.Marshal(v)
+	_ = json.Unmarshal(bytes, &result)
+	return result
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mridulganga/dlt-manager/pkg/db"
+)
+
+type target struct {
+	Name  string   `json:"name"`
+	TPS   float64  `json:"tps"`
+	Tags  []string `json:"tags,omitempty"`
+	Owner string   `json:"owner"`
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before any
+		after  any
+		want   map[string]db.Change
+	}{
+		{
+			name:   "unchanged",
+			before: target{Name: "a", TPS: 10},
+			after:  target{Name: "a", TPS: 10},
+			want:   map[string]db.Change{},
+		},
+		{
+			name:   "changed fields only",
+			before: target{Name: "a", TPS: 10, Owner: "x"},
+			after:  target{Name: "a", TPS: 20, Owner: "y"},
+			want: map[string]db.Change{
+				"tps":   {Before: 10.0, After: 20.0},
+				"owner": {Before: "x", After: "y"},
+			},
+		},
+		{
+			name:   "added and removed fields",
+			before: target{Name: "a", Tags: []string{"x"}},
+			after:  map[string]any{"name": "a", "tps": 0, "owner": "", "extra": true},
+			want: map[string]db.Change{
+				"tags":  {Before: []any{"x"}, After: nil},
+				"extra": {Before: nil, After: true},
+			},
+		},
+		{
+			name:   "created",
+			before: nil,
+			after:  &target{Name: "a"},
+			want: map[string]db.Change{
+				"name":  {Before: nil, After: "a"},
+				"tps":   {Before: nil, After: 0.0},
+				"owner": {Before: nil, After: ""},
+			},
+		},
+		{
+			name:   "deleted through a nil pointer",
+			before: &target{Name: "a"},
+			after:  (*target)(nil),
+			want: map[string]db.Change{
+				"name":  {Before: "a", After: nil},
+				"tps":   {Before: 0.0, After: nil},
+				"owner": {Before: "", After: nil},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
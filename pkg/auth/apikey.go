@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	APIKeyHeader     = api.APIKeyHeader
+	apiKeyPrefix     = "dlt_"
+	enrollmentPrefix = "dle_"
+)
+
+// GenerateAPIKey - new random key, only the hash of it is stored
+func GenerateAPIKey() (key string, keyHash string, err error) {
+	return generateSecret(apiKeyPrefix)
+}
+
+// GenerateEnrollmentToken - new random node group enrollment token, only the
+// hash of it is stored
+func GenerateEnrollmentToken() (token string, tokenHash string, err error) {
+	return generateSecret(enrollmentPrefix)
+}
+
+func generateSecret(prefix string) (string, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	key := prefix + hex.EncodeToString(secret)
+	return key, HashAPIKey(key), nil
+}
+
+// HashAPIKey - keys are random so a plain sha256 is enough to look them up
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuthenticateAPIKey - resolve a key to its owner, the user role is narrowed
+// down to the scope of the key
+func (a *Auth) AuthenticateAPIKey(key string) (*db.User, *db.APIKey, error) {
+	apiKey, err := a.d.GetAPIKeyByHash(HashAPIKey(key))
+	if err != nil || !apiKey.IsActive() {
+		return nil, nil, apierr.Unauthorized("invalid api key")
+	}
+
+	user, err := a.d.GetUserByID(apiKey.UserID)
+	if err != nil {
+		return nil, nil, apierr.Unauthorized("invalid api key")
+	}
+	if user.HasRole(apiKey.Scope) {
+		user.Role = apiKey.Scope
+	}
+
+	apiKey, err = a.d.UpdateAPIKey(apiKey.ID, bson.M{"last_used_at": time.Now()})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, apiKey, nil
+}
+
+// CurrentAPIKey - key used to authenticate the request, nil for session tokens
+func CurrentAPIKey(c *gin.Context) *db.APIKey {
+	apiKey, _ := c.Get(apiKeyKey)
+	k, _ := apiKey.(*db.APIKey)
+	return k
+}
+
+// RequireSession - reject requests authenticated with an api key, used for
+// routes which manage credentials
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentSession(c) == nil {
+			apierr.Abort(c, apierr.Forbidden("session login required"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	userKey    = "auth_user"
+	sessionKey = "auth_session"
+	apiKeyKey  = "auth_api_key"
+)
+
+var ErrInvalidCredentials = apierr.Unauthorized("invalid email or password")
+
+// Auth - password login and signed session tokens, a token is a jwt whose id
+// refers to a session in the db so that it can be revoked on logout
+type Auth struct {
+	d      *db.DB
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewAuth(database *db.DB, secret string, ttl time.Duration) *Auth {
+	return &Auth{
+		d:      database,
+		secret: []byte(secret),
+		ttl:    ttl,
+	}
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func CheckPassword(hash string, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NormalizeEmail - emails are stored and looked up trimmed and lower case
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Login - check the credentials and start a new session, returns the session token
+func (a *Auth) Login(email string, password string) (string, *db.User, error) {
+	user, err := a.d.GetUserByEmail(NormalizeEmail(email))
+	if err != nil || !CheckPassword(user.PasswordHash, password) {
+		return "", nil, ErrInvalidCredentials
+	}
+
+	session, err := a.d.CreateSession(&db.Session{
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(a.ttl),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ID:        session.ID,
+		Subject:   user.ID,
+		IssuedAt:  jwt.NewNumericDate(session.CreatedAt),
+		ExpiresAt: jwt.NewNumericDate(session.ExpiresAt),
+	}).SignedString(a.secret)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, user, nil
+}
+
+func (a *Auth) Logout(session *db.Session) error {
+	return a.d.DeleteSession(session.ID)
+}
+
+// Authenticate - verify a session token, returns the user and session it belongs to
+func (a *Auth) Authenticate(token string) (*db.User, *db.Session, error) {
+	claims := jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, nil, apierr.Unauthorized("invalid token %s", err.Error())
+	}
+
+	session, err := a.d.GetSessionByID(claims.ID)
+	if err != nil || session.UserID != claims.Subject || session.ExpiresAt.Before(time.Now()) {
+		return nil, nil, apierr.Unauthorized("session expired")
+	}
+
+	user, err := a.d.GetUserByID(session.UserID)
+	if err != nil {
+		return nil, nil, apierr.Unauthorized("session expired")
+	}
+
+	return user, session, nil
+}
+
+// Middleware - reject requests without a valid bearer token or api key, the
+// user and session or key are available to handlers through CurrentUser,
+// CurrentSession and CurrentAPIKey
+func (a *Auth) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			user, apiKey, err := a.AuthenticateAPIKey(key)
+			if err != nil {
+				apierr.Abort(c, err)
+				return
+			}
+			c.Set(userKey, user)
+			c.Set(apiKeyKey, apiKey)
+			c.Next()
+			return
+		}
+
+		token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || token == "" {
+			apierr.Abort(c, apierr.Unauthorized("authentication required"))
+			return
+		}
+
+		user, session, err := a.Authenticate(token)
+		if err != nil {
+			apierr.Abort(c, err)
+			return
+		}
+
+		c.Set(userKey, user)
+		c.Set(sessionKey, session)
+		c.Next()
+	}
+}
+
+func CurrentUser(c *gin.Context) *db.User {
+	user, _ := c.Get(userKey)
+	u, _ := user.(*db.User)
+	return u
+}
+
+func CurrentSession(c *gin.Context) *db.Session {
+	session, _ := c.Get(sessionKey)
+	s, _ := session.(*db.Session)
+	return s
+}
+
+// RequireRole - reject requests of users without at least the given role, must
+// run after Middleware
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := CurrentUser(c)
+		if user == nil || !user.HasRole(role) {
+			apierr.Abort(c, apierr.Forbidden("%s role required", role))
+			return
+		}
+		c.Next()
+	}
+}
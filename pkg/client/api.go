@@ -0,0 +1,398 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/api"
+)
+
+// auth
+
+func (c *Client) Register(req api.RegisterRequest) (*api.User, error) {
+	result := api.User{}
+	return &result, c.do(http.MethodPost, "/auth/register", nil, req, &result)
+}
+
+type LoginResponse struct {
+	Token string   `json:"token"`
+	User  api.User `json:"user"`
+}
+
+// Login - log in and use the session token for the following calls
+func (c *Client) Login(req api.LoginRequest) (*LoginResponse, error) {
+	result := LoginResponse{}
+	if err := c.do(http.MethodPost, "/auth/login", nil, req, &result); err != nil {
+		return nil, err
+	}
+	c.SetToken(result.Token)
+	return &result, nil
+}
+
+func (c *Client) Logout() error {
+	return c.do(http.MethodPost, "/auth/logout", nil, nil, nil)
+}
+
+func (c *Client) Me() (*api.User, error) {
+	result := api.User{}
+	return &result, c.do(http.MethodGet, "/auth/me", nil, nil, &result)
+}
+
+// projects
+
+func (c *Client) CreateProject(req api.CreateProjectRequest) (*api.Project, error) {
+	result := api.Project{}
+	return &result, c.do(http.MethodPut, "/api/projects", nil, req, &result)
+}
+
+func (c *Client) ListProjects() ([]api.Project, error) {
+	result := []api.Project{}
+	return result, c.do(http.MethodGet, "/api/projects", nil, nil, &result)
+}
+
+func (c *Client) GetProject(id string) (*api.Project, error) {
+	result := api.Project{}
+	return &result, c.do(http.MethodGet, pathID("/api/projects/%s", id), nil, nil, &result)
+}
+
+func (c *Client) UpdateProject(id string, req api.UpdateProjectRequest) (*api.Project, error) {
+	result := api.Project{}
+	return &result, c.do(http.MethodPatch, pathID("/api/projects/%s", id), nil, req, &result)
+}
+
+func (c *Client) DeleteProject(id string) error {
+	return c.do(http.MethodDelete, pathID("/api/projects/%s", id), nil, nil, nil)
+}
+
+func (c *Client) AddProjectMember(id string, userId string) (*api.Project, error) {
+	result := api.Project{}
+	req := api.AddProjectMemberRequest{UserID: userId}
+	return &result, c.do(http.MethodPut, pathID("/api/projects/%s/members", id), nil, req, &result)
+}
+
+func (c *Client) RemoveProjectMember(id string, userId string) (*api.Project, error) {
+	result := api.Project{}
+	return &result, c.do(http.MethodDelete, pathID("/api/projects/%s/members/%s", id, userId), nil, nil, &result)
+}
+
+// node groups
+
+type NodeGroupFilter struct {
+	Namespace    *string
+	IsHealthy    *bool
+	HealthStatus string
+	Pending      *bool
+	Cordoned     *bool
+	ConfigDrift  *bool
+}
+
+func (c *Client) ListNodeGroups(f NodeGroupFilter, opts ListOptions) (*api.Page[api.NodeGroup], error) {
+	q := opts.query()
+	if f.Namespace != nil {
+		q.Set("namespace", *f.Namespace)
+	}
+	if f.IsHealthy != nil {
+		q.Set("is_healthy", strconv.FormatBool(*f.IsHealthy))
+	}
+	setQuery(q, "health_status", f.HealthStatus)
+	if f.Pending != nil {
+		q.Set("pending", strconv.FormatBool(*f.Pending))
+	}
+	if f.Cordoned != nil {
+		q.Set("cordoned", strconv.FormatBool(*f.Cordoned))
+	}
+	if f.ConfigDrift != nil {
+		q.Set("config_drift", strconv.FormatBool(*f.ConfigDrift))
+	}
+	result := api.Page[api.NodeGroup]{}
+	return &result, c.do(http.MethodGet, "/api/ngs", q, nil, &result)
+}
+
+func (c *Client) CreateNodeGroup(req api.CreateNodeGroupRequest) (*api.NodeGroup, error) {
+	result := api.NodeGroup{}
+	return &result, c.do(http.MethodPut, "/api/ngs", nil, req, &result)
+}
+
+func (c *Client) GetNodeGroup(id string) (*api.NodeGroup, error) {
+	result := api.NodeGroup{}
+	return &result, c.do(http.MethodGet, pathID("/api/ngs/%s", id), nil, nil, &result)
+}
+
+func (c *Client) UpdateNodeGroup(id string, req api.UpdateNodeGroupRequest) (*api.NodeGroup, error) {
+	result := api.NodeGroup{}
+	return &result, c.do(http.MethodPatch, pathID("/api/ngs/%s", id), nil, req, &result)
+}
+
+// GetNodeGroupNodes - nodes of a node group and up to history of their latest
+// events, history 0 uses the server default
+func (c *Client) GetNodeGroupNodes(id string, history int64) (*api.NodeGroupNodes, error) {
+	q := url.Values{}
+	if history > 0 {
+		q.Set("history", strconv.FormatInt(history, 10))
+	}
+	result := api.NodeGroupNodes{}
+	return &result, c.do(http.MethodGet, pathID("/api/ngs/%s/nodes", id), q, nil, &result)
+}
+
+// GetNodeGroupHealth - health timeline and uptime of a node group, zero times
+// use the server defaults of the last 7 days
+func (c *Client) GetNodeGroupHealth(id string, from time.Time, to time.Time) (*api.NodeGroupHealthReport, error) {
+	q := url.Values{}
+	setTimeQuery(q, "from", from)
+	setTimeQuery(q, "to", to)
+	result := api.NodeGroupHealthReport{}
+	return &result, c.do(http.MethodGet, pathID("/api/ngs/%s/health", id), q, nil, &result)
+}
+
+func (c *Client) GetNodeGroupConfig(id string) (*api.NodeGroupConfigStatus, error) {
+	result := api.NodeGroupConfigStatus{}
+	return &result, c.do(http.MethodGet, pathID("/api/ngs/%s/config", id), nil, nil, &result)
+}
+
+// SetNodeGroupConfig - replace the desired config of a node group
+func (c *Client) SetNodeGroupConfig(id string, req api.SetNodeGroupConfigRequest) (*api.NodeGroupConfigStatus, error) {
+	result := api.NodeGroupConfigStatus{}
+	return &result, c.do(http.MethodPut, pathID("/api/ngs/%s/config", id), nil, req, &result)
+}
+
+func (c *Client) DeleteNodeGroup(id string) error {
+	return c.do(http.MethodDelete, pathID("/api/ngs/%s", id), nil, nil, nil)
+}
+
+// ApproveNodeGroup - approve a node group which registered itself
+func (c *Client) ApproveNodeGroup(id string) (*api.NodeGroup, error) {
+	result := api.NodeGroup{}
+	return &result, c.do(http.MethodPut, pathID("/api/ngs/%s/approve", id), nil, nil, &result)
+}
+
+// CordonNodeGroup - stop giving the node group new load tests
+func (c *Client) CordonNodeGroup(id string) (*api.NodeGroup, error) {
+	result := api.NodeGroup{}
+	return &result, c.do(http.MethodPut, pathID("/api/ngs/%s/cordon", id), nil, nil, &result)
+}
+
+func (c *Client) UncordonNodeGroup(id string) (*api.NodeGroup, error) {
+	result := api.NodeGroup{}
+	return &result, c.do(http.MethodPut, pathID("/api/ngs/%s/uncordon", id), nil, nil, &result)
+}
+
+// DrainNodeGroup - stop the load tests of the node group and cordon it
+func (c *Client) DrainNodeGroup(id string) (*api.NodeGroup, error) {
+	result := api.NodeGroup{}
+	return &result, c.do(http.MethodPut, pathID("/api/ngs/%s/drain", id), nil, nil, &result)
+}
+
+// load tests
+
+type LoadTestFilter struct {
+	Status    string
+	CreatedBy string
+	From      time.Time
+	To        time.Time
+	Text      string
+}
+
+func (c *Client) ListLoadTests(f LoadTestFilter, opts ListOptions) (*api.Page[api.LoadTest], error) {
+	q := opts.query()
+	setQuery(q, "status", f.Status)
+	setQuery(q, "created_by", f.CreatedBy)
+	setQuery(q, "q", f.Text)
+	setTimeQuery(q, "from", f.From)
+	setTimeQuery(q, "to", f.To)
+	result := api.Page[api.LoadTest]{}
+	return &result, c.do(http.MethodGet, "/api/loadtests", q, nil, &result)
+}
+
+func (c *Client) CreateLoadTest(req api.CreateLoadTestRequest) (*api.LoadTest, error) {
+	result := api.LoadTest{}
+	return &result, c.do(http.MethodPut, "/api/loadtests", nil, req, &result)
+}
+
+func (c *Client) GetLoadTest(id string) (*api.LoadTest, error) {
+	result := api.LoadTest{}
+	return &result, c.do(http.MethodGet, pathID("/api/loadtests/%s", id), nil, nil, &result)
+}
+
+func (c *Client) UpdateLoadTest(id string, req api.UpdateLoadTestRequest) (*api.LoadTest, error) {
+	result := api.LoadTest{}
+	return &result, c.do(http.MethodPatch, pathID("/api/loadtests/%s", id), nil, req, &result)
+}
+
+func (c *Client) DeleteLoadTest(id string) error {
+	return c.do(http.MethodDelete, pathID("/api/loadtests/%s", id), nil, nil, nil)
+}
+
+// StopLoadTest - stop a load test, admins can leave out the id to stop
+// everything running in the namespace
+func (c *Client) StopLoadTest(id string, namespace string) error {
+	q := url.Values{}
+	setQuery(q, "id", id)
+	setQuery(q, "namespace", namespace)
+	return c.do(http.MethodPut, "/api/loadtests/stop", q, nil, nil)
+}
+
+// ListQueue - queued load tests in the order they start, empty namespace for
+// all namespaces
+func (c *Client) ListQueue(namespace string) ([]api.LoadTest, error) {
+	q := url.Values{}
+	setQuery(q, "namespace", namespace)
+	result := []api.LoadTest{}
+	return result, c.do(http.MethodGet, "/api/queue", q, nil, &result)
+}
+
+// ReorderQueuedLoadTest - change the priority of a queued load test, higher
+// priorities start first
+func (c *Client) ReorderQueuedLoadTest(id string, priority int) (*api.LoadTest, error) {
+	result := api.LoadTest{}
+	req := api.ReorderQueuedLoadTestRequest{Priority: priority}
+	return &result, c.do(http.MethodPatch, pathID("/api/queue/%s", id), nil, req, &result)
+}
+
+func (c *Client) CancelQueuedLoadTest(id string) (*api.LoadTest, error) {
+	result := api.LoadTest{}
+	return &result, c.do(http.MethodDelete, pathID("/api/queue/%s", id), nil, nil, &result)
+}
+
+// LoadTestResults - aggregated results of a load test
+type LoadTestResults struct {
+	LoadTestID     string            `json:"load_test_id"`
+	StartTime      time.Time         `json:"startTime"`
+	EndTime        time.Time         `json:"endTime"`
+	Duration       int               `json:"duration"`
+	TPS            float64           `json:"tps"`
+	TotalRequests  int               `json:"totalRequests"`
+	SuccessCount   int               `json:"successCount"`
+	FailureCount   int               `json:"failureCount"`
+	SuccessPercent float64           `json:"successPercent"`
+	AvgLatencyMs   float64           `json:"avgLatencyMs"`
+	TopFailures    map[string]string `json:"topFailures"`
+}
+
+// PercentSuccessful - share of successful requests from 0 to 100, computed
+// from the counts as older managers report successPercent as a ratio of 0 or 1
+func (r LoadTestResults) PercentSuccessful() float64 {
+	if r.TotalRequests == 0 {
+		return 0
+	}
+	return float64(r.SuccessCount) * 100 / float64(r.TotalRequests)
+}
+
+func (c *Client) GetLoadTestResults(id string) (*LoadTestResults, error) {
+	result := LoadTestResults{}
+	return &result, c.do(http.MethodGet, pathID("/api/loadtests/%s/results", id), nil, nil, &result)
+}
+
+// dead letters
+
+func (c *Client) ListDeadLetters() ([]api.DeadLetter, error) {
+	result := []api.DeadLetter{}
+	return result, c.do(http.MethodGet, "/api/deadletters", nil, nil, &result)
+}
+
+func (c *Client) GetDeadLetter(id string) (*api.DeadLetter, error) {
+	result := api.DeadLetter{}
+	return &result, c.do(http.MethodGet, pathID("/api/deadletters/%s", id), nil, nil, &result)
+}
+
+func (c *Client) ReplayDeadLetter(id string) error {
+	return c.do(http.MethodPut, pathID("/api/deadletters/%s/replay", id), nil, nil, nil)
+}
+
+func (c *Client) DeleteDeadLetter(id string) error {
+	return c.do(http.MethodDelete, pathID("/api/deadletters/%s", id), nil, nil, nil)
+}
+
+// PurgeDeadLetters - delete all dead letters, returns how many were deleted
+func (c *Client) PurgeDeadLetters() (int64, error) {
+	result := struct {
+		Deleted int64 `json:"deleted"`
+	}{}
+	err := c.do(http.MethodDelete, "/api/deadletters", nil, nil, &result)
+	return result.Deleted, err
+}
+
+// api keys
+
+type CreateAPIKeyResponse struct {
+	Key    string     `json:"key"`
+	APIKey api.APIKey `json:"api_key"`
+}
+
+func (c *Client) CreateAPIKey(req api.CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	result := CreateAPIKeyResponse{}
+	return &result, c.do(http.MethodPut, "/api/apikeys", nil, req, &result)
+}
+
+func (c *Client) ListAPIKeys() ([]api.APIKey, error) {
+	result := []api.APIKey{}
+	return result, c.do(http.MethodGet, "/api/apikeys", nil, nil, &result)
+}
+
+func (c *Client) RevokeAPIKey(id string) (*api.APIKey, error) {
+	result := api.APIKey{}
+	return &result, c.do(http.MethodDelete, pathID("/api/apikeys/%s", id), nil, nil, &result)
+}
+
+// enrollment tokens
+
+type CreateEnrollmentTokenResponse struct {
+	Token           string              `json:"token"`
+	EnrollmentToken api.EnrollmentToken `json:"enrollment_token"`
+}
+
+func (c *Client) CreateEnrollmentToken(req api.CreateEnrollmentTokenRequest) (*CreateEnrollmentTokenResponse, error) {
+	result := CreateEnrollmentTokenResponse{}
+	return &result, c.do(http.MethodPut, "/api/enrollmenttokens", nil, req, &result)
+}
+
+func (c *Client) ListEnrollmentTokens() ([]api.EnrollmentToken, error) {
+	result := []api.EnrollmentToken{}
+	return result, c.do(http.MethodGet, "/api/enrollmenttokens", nil, nil, &result)
+}
+
+func (c *Client) RevokeEnrollmentToken(id string) (*api.EnrollmentToken, error) {
+	result := api.EnrollmentToken{}
+	return &result, c.do(http.MethodDelete, pathID("/api/enrollmenttokens/%s", id), nil, nil, &result)
+}
+
+// audit and users
+
+func (c *Client) ListAudit(f api.AuditFilter) ([]api.AuditEntry, error) {
+	q := url.Values{}
+	setQuery(q, "actor", f.Actor)
+	setQuery(q, "action", f.Action)
+	setQuery(q, "target_type", f.TargetType)
+	setQuery(q, "target_id", f.TargetID)
+	setTimeQuery(q, "from", f.From)
+	setTimeQuery(q, "to", f.To)
+	if f.Limit > 0 {
+		q.Set("limit", strconv.FormatInt(f.Limit, 10))
+	}
+	result := []api.AuditEntry{}
+	return result, c.do(http.MethodGet, "/api/audit", q, nil, &result)
+}
+
+func (c *Client) ListUsers(opts ListOptions) (*api.Page[api.User], error) {
+	result := api.Page[api.User]{}
+	return &result, c.do(http.MethodGet, "/api/users", opts.query(), nil, &result)
+}
+
+func (c *Client) UpdateUserRole(id string, role string) (*api.User, error) {
+	result := api.User{}
+	req := api.UpdateUserRoleRequest{Role: role}
+	return &result, c.do(http.MethodPatch, pathID("/api/users/%s/role", id), nil, req, &result)
+}
+
+func setQuery(q url.Values, name string, value string) {
+	if value != "" {
+		q.Set(name, value)
+	}
+}
+
+func setTimeQuery(q url.Values, name string, value time.Time) {
+	if !value.IsZero() {
+		q.Set(name, value.Format(time.RFC3339))
+	}
+}
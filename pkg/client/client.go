@@ -0,0 +1,167 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/api"
+)
+
+// Client - typed client of the manager api described by pkg/openapi
+type Client struct {
+	baseURL   string
+	token     string
+	apiKey    string
+	projectID string
+	http      *http.Client
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SetToken - authenticate with a session token returned by Login
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+// SetAPIKey - authenticate with an api key, takes precedence over the token
+func (c *Client) SetAPIKey(key string) {
+	c.apiKey = key
+}
+
+// SetProject - project the node group and load test calls work in
+func (c *Client) SetProject(projectId string) {
+	c.projectID = projectId
+}
+
+// SetHTTPClient - replace the http client, eg. to change the timeout
+func (c *Client) SetHTTPClient(h *http.Client) {
+	c.http = h
+}
+
+// ListOptions - pagination of list calls, zero values use the server defaults
+type ListOptions struct {
+	Offset int64
+	Limit  int64
+	Sort   string
+}
+
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	if o.Offset > 0 {
+		q.Set("offset", strconv.FormatInt(o.Offset, 10))
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.FormatInt(o.Limit, 10))
+	}
+	if o.Sort != "" {
+		q.Set("sort", o.Sort)
+	}
+	return q
+}
+
+// newRequest - request to the api with the authentication and project headers
+func (c *Client) newRequest(method string, path string, query url.Values, body any) (*http.Request, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u = u + "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set(api.APIKeyHeader, c.apiKey)
+	} else if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.projectID != "" {
+		req.Header.Set(api.ProjectHeader, c.projectID)
+	}
+	return req, nil
+}
+
+// do - send a request and decode the json response into out, errors of the
+// api are returned as *api.Error
+func (c *Client) do(method string, path string, query url.Values, body any, out any) error {
+	req, err := c.newRequest(method, path, query, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return responseError(req, resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response of %s %s %w", method, path, err)
+	}
+	return nil
+}
+
+// responseError - error of a response which didn't succeed
+func responseError(req *http.Request, resp *http.Response) error {
+	errResp := struct {
+		Error *api.Error `json:"error"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == nil {
+		return &api.Error{Status: resp.StatusCode, Message: fmt.Sprintf("%s %s failed with status %d", req.Method, req.URL.Path, resp.StatusCode)}
+	}
+	errResp.Error.Status = resp.StatusCode
+	return errResp.Error
+}
+
+// Status - response of calls which only report a status
+type Status struct {
+	Status string `json:"status"`
+}
+
+func (c *Client) Health() (*Status, error) {
+	result := Status{}
+	return &result, c.do(http.MethodGet, "/", nil, nil, &result)
+}
+
+// OpenAPI - specification served by the manager
+func (c *Client) OpenAPI() (map[string]any, error) {
+	result := map[string]any{}
+	return result, c.do(http.MethodGet, "/api/openapi.json", nil, nil, &result)
+}
+
+func pathID(format string, ids ...string) string {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = url.PathEscape(id)
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,24 @@
+package client
+
+import (
+	"go/build"
+	"strings"
+	"testing"
+)
+
+// the client is imported by tools which must not pull in the database,
+// transports or http server of the manager
+func TestImportsOnlyAPI(t *testing.T) {
+	for _, dir := range []string{".", "../api"} {
+		pkg, err := build.ImportDir(dir, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, imp := range pkg.Imports {
+			isStd := !strings.Contains(strings.Split(imp, "/")[0], ".")
+			if !isStd && imp != "github.com/mridulganga/dlt-manager/pkg/api" {
+				t.Errorf("%s imports %s", pkg.Name, imp)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/mridulganga/dlt-manager/pkg/api"
+)
+
+// StreamEvent - event of a load test stream, decode the data with the method
+// matching the type
+type StreamEvent struct {
+	Type       string          `json:"type"`
+	LoadTestID string          `json:"load_test_id"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func (e StreamEvent) Status() (string, error) {
+	status := api.StatusChange{}
+	err := json.Unmarshal(e.Data, &status)
+	return status.Status, err
+}
+
+func (e StreamEvent) Stats() (*api.Stats, error) {
+	stats := api.Stats{}
+	return &stats, json.Unmarshal(e.Data, &stats)
+}
+
+func (e StreamEvent) Summary() (*LoadTestResults, error) {
+	summary := LoadTestResults{}
+	return &summary, json.Unmarshal(e.Data, &summary)
+}
+
+// StreamLoadTest - call handle with every event of a load test until the
+// summary arrives, the stream ends or handle returns an error
+func (c *Client) StreamLoadTest(id string, handle func(StreamEvent) error) error {
+	req, err := c.newRequest(http.MethodGet, pathID("/api/loadtests/%s/stream", id), nil, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// the stream lasts as long as the load test, so no client timeout
+	h := *c.http
+	h.Timeout = 0
+	resp, err := h.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return responseError(req, resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	data := []string{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case line == "" && len(data) > 0:
+			e := StreamEvent{}
+			if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &e); err != nil {
+				return err
+			}
+			data = data[:0]
+			if err := handle(e); err != nil {
+				return err
+			}
+			if e.Type == api.EventSummary {
+				return nil
+			}
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (d DB) CreateAPIKey(apiKey *APIKey) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	apiKey.ID = uuid.New().String()
+	apiKey.CreatedAt = time.Now()
+
+	collection := d.client.Database(d.database).Collection(apiKeyColl)
+	_, err := collection.InsertOne(ctx, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+func (d DB) GetAPIKeyByID(id string) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var apiKey APIKey
+	collection := d.client.Database(d.database).Collection(apiKeyColl)
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func (d DB) GetAPIKeyByHash(keyHash string) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var apiKey APIKey
+	collection := d.client.Database(d.database).Collection(apiKeyColl)
+	err := collection.FindOne(ctx, bson.M{"key_hash": keyHash}).Decode(&apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func (d DB) UpdateAPIKey(id string, update bson.M) (*APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var apiKey APIKey
+	collection := d.client.Database(d.database).Collection(apiKeyColl)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": update}, opts).Decode(&apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func (d DB) ListAPIKey() (*[]APIKey, error) {
+	return d.listAPIKey(bson.M{})
+}
+
+func (d DB) ListAPIKeyByUser(userId string) (*[]APIKey, error) {
+	return d.listAPIKey(bson.M{"user_id": userId})
+}
+
+func (d DB) listAPIKey(filter bson.M) (*[]APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(apiKeyColl)
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	apiKeys := []APIKey{}
+
+	for cursor.Next(ctx) {
+		var apiKey APIKey
+		err := cursor.Decode(&apiKey)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return &apiKeys, nil
+}
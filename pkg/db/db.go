@@ -5,10 +5,12 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"strconv"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mridulganga/dlt-manager/pkg/api"
 	"github.com/mridulganga/dlt-manager/pkg/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -21,6 +23,16 @@ const (
 	loadtestColl        = "loadtests"
 	loadTestUpdatesColl = "loadtestupdates"
 	ltsummaryColl       = "ltsummary"
+	deadLetterColl      = "deadletters"
+	sessionColl         = "sessions"
+	apiKeyColl          = "apikeys"
+	auditColl           = "audit"
+	projectColl         = "projects"
+	nodeColl            = "nodes"
+	nodeEventColl       = "nodeevents"
+	enrollmentColl      = "enrollmenttokens"
+	healthEventColl     = "healthevents"
+	metaColl            = "meta"
 )
 
 type DBInterface interface{}
@@ -48,6 +60,24 @@ func NewDatabase(connString string, dbName string) (*DB, error) {
 	return &db, nil
 }
 
+// CreateIndexes - unique indexes the api relies on, fails when existing
+// documents break them
+func (d DB) CreateIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(userColl)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("error while creating the unique users email index %s", err.Error())
+	}
+
+	return nil
+}
+
 func (d DB) CreateUser(user *User) (*User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -120,6 +150,41 @@ func (d DB) DeleteUser(id string) error {
 	return nil
 }
 
+// ClaimFirstAdmin - only the first user to claim it administers the manager,
+// returns false once someone else claimed it
+func (d DB) ClaimFirstAdmin(userId string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(metaColl)
+	_, err := collection.InsertOne(ctx, bson.M{"_id": "first_admin", "user_id": userId, "created_at": time.Now()})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (d DB) CountUser() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(userColl)
+	return collection.CountDocuments(ctx, bson.M{})
+}
+
+func (d DB) ListUserPage(opts ListOptions) (*api.Page[User], error) {
+	if err := validateSort(userColl, opts.Sort); err != nil {
+		return nil, err
+	}
+
+	collection := d.client.Database(d.database).Collection(userColl)
+	return findPage[User](collection, bson.M{}, opts)
+}
+
 func (d DB) ListUser() (*[]User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -154,6 +219,9 @@ func (d DB) CreateLoadTest(loadtest *LoadTest) (*LoadTest, error) {
 	defer cancel()
 
 	loadtest.StartTime = time.Now()
+	if loadtest.Status == "queued" {
+		loadtest.QueuedAt = loadtest.StartTime
+	}
 	loadtest.ID = uuid.New().String()
 
 	collection := d.client.Database(d.database).Collection(loadtestColl)
@@ -194,6 +262,53 @@ func (d DB) UpdateLoadTest(id string, update bson.M) (*LoadTest, error) {
 	return &loadtest, nil
 }
 
+// UpdateLoadTestIfStatus - update a load test only while it still has status,
+// mongo.ErrNoDocuments when it doesn't
+func (d DB) UpdateLoadTestIfStatus(id string, status string, update bson.M) (*LoadTest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var loadtest LoadTest
+	collection := d.client.Database(d.database).Collection(loadtestColl)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": id, "status": status}, bson.M{"$set": update}, opts).Decode(&loadtest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &loadtest, nil
+}
+
+// MarkLoadTestNodeGroupRunning - the node group reported running the load
+// test, it is no longer done in case it was lost before
+func (d DB) MarkLoadTestNodeGroupRunning(id string, ngId string) (*LoadTest, error) {
+	return d.updateLoadTestNodeGroups(id, bson.M{
+		"$addToSet": bson.M{"started_node_groups": ngId},
+		"$pull":     bson.M{"done_node_groups": ngId},
+	})
+}
+
+// MarkLoadTestNodeGroupDone - the node group stopped running the load test or
+// was lost
+func (d DB) MarkLoadTestNodeGroupDone(id string, ngId string) (*LoadTest, error) {
+	return d.updateLoadTestNodeGroups(id, bson.M{"$addToSet": bson.M{"done_node_groups": ngId}})
+}
+
+func (d DB) updateLoadTestNodeGroups(id string, update bson.M) (*LoadTest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var loadtest LoadTest
+	collection := d.client.Database(d.database).Collection(loadtestColl)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&loadtest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &loadtest, nil
+}
+
 func (d DB) DeleteLoadTest(id string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -208,11 +323,114 @@ func (d DB) DeleteLoadTest(id string) error {
 }
 
 func (d DB) ListLoadTest() (*[]LoadTest, error) {
+	return d.listLoadTest(bson.M{})
+}
+
+// ListLoadTestPage - one page of the load tests matching the filter, Text
+// searches the description
+func (d DB) ListLoadTestPage(f LoadTestFilter, opts ListOptions) (*api.Page[LoadTest], error) {
+	if err := validateSort(loadtestColl, opts.Sort); err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{}
+	if f.ProjectID != "" {
+		filter["project_id"] = f.ProjectID
+	}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+	if f.CreatedBy != "" {
+		filter["created_by"] = f.CreatedBy
+	}
+	startTime := bson.M{}
+	if !f.From.IsZero() {
+		startTime["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		startTime["$lte"] = f.To
+	}
+	if len(startTime) > 0 {
+		filter["start_time"] = startTime
+	}
+	if f.Text != "" {
+		filter["description"] = bson.M{"$regex": regexp.QuoteMeta(f.Text), "$options": "i"}
+	}
+
+	collection := d.client.Database(d.database).Collection(loadtestColl)
+	return findPage[LoadTest](collection, filter, opts)
+}
+
+// runningStatuses - load tests node groups run, see LoadTest.IsRunning
+var runningStatuses = bson.A{"running", "stopping"}
+
+// ListRunningLoadTestForNodeGroup - running load tests the node group takes part
+// in, all of its project or of every project when it is shared
+func (d DB) ListRunningLoadTestForNodeGroup(ng NodeGroup) (*[]LoadTest, error) {
+	filter := bson.M{
+		"status":    bson.M{"$in": runningStatuses},
+		"namespace": ng.Namespace,
+		// load tests without node groups ran on all of them
+		"$or": bson.A{bson.M{"node_groups": ng.ID}, bson.M{"node_groups.0": bson.M{"$exists": false}}},
+	}
+	if !ng.Shared {
+		filter["project_id"] = ng.ProjectID
+	}
+	return d.listLoadTest(filter)
+}
+
+func (d DB) ListRunningLoadTestByNamespace(namespace string) (*[]LoadTest, error) {
+	return d.listLoadTest(bson.M{"status": bson.M{"$in": runningStatuses}, "namespace": namespace})
+}
+
+// CountActiveLoadTest - number of load tests of the project which are still
+// running or wait in the queue
+func (d DB) CountActiveLoadTest(projectId string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(loadtestColl)
+	return collection.CountDocuments(ctx, bson.M{"project_id": projectId, "status": bson.M{"$in": append(bson.A{"queued"}, runningStatuses...)}})
+}
+
+// ListQueuedLoadTest - queued load tests in the order they are started, empty
+// projectId or namespace match all
+func (d DB) ListQueuedLoadTest(projectId string, namespace string) (*[]LoadTest, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	filter := bson.M{"status": "queued"}
+	if projectId != "" {
+		filter["project_id"] = projectId
+	}
+	if namespace != "" {
+		filter["namespace"] = namespace
+	}
 	collection := d.client.Database(d.database).Collection(loadtestColl)
-	cursor, err := collection.Find(ctx, bson.M{})
+	opts := options.Find().SetSort(bson.D{{Key: "priority", Value: -1}, {Key: "queued_at", Value: 1}})
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	loadtests := []LoadTest{}
+	for cursor.Next(ctx) {
+		var loadtest LoadTest
+		if err := cursor.Decode(&loadtest); err != nil {
+			return nil, err
+		}
+		loadtests = append(loadtests, loadtest)
+	}
+	return &loadtests, nil
+}
+
+func (d DB) listLoadTest(filter bson.M) (*[]LoadTest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(loadtestColl)
+	cursor, err := collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -240,8 +458,12 @@ func (d DB) CreateNodeGroup(nodegroup *NodeGroup) (*NodeGroup, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	nodegroup.ID = uuid.New().String()
-	nodegroup.LastHealthCheck = time.Now()
+	// registered node groups bring their own id
+	if nodegroup.ID == "" {
+		nodegroup.ID = uuid.New().String()
+	}
+	// last_health_time stays unset until the first heartbeat, which starts the
+	// health history of the node group
 
 	collection := d.client.Database(d.database).Collection(ngColl)
 	_, err := collection.InsertOne(ctx, nodegroup)
@@ -281,6 +503,34 @@ func (d DB) UpdateNodeGroup(id string, update bson.M) (*NodeGroup, error) {
 	return &nodegroup, nil
 }
 
+// SetNodeGroupConfig - replace the desired config of a node group with the
+// next version
+func (d DB) SetNodeGroupConfig(id string, config NodeGroupConfig) (*NodeGroup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"config.heartbeat_interval": config.HeartbeatInterval,
+			"config.result_batch_size":  config.ResultBatchSize,
+			"config.log_level":          config.LogLevel,
+			"config.max_concurrency":    config.MaxConcurrency,
+			"config.updated_by":         config.UpdatedBy,
+			"config.updated_at":         time.Now(),
+		},
+		"$inc": bson.M{"config.version": 1},
+	}
+
+	var nodegroup NodeGroup
+	collection := d.client.Database(d.database).Collection(ngColl)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&nodegroup)
+	if err != nil {
+		return nil, err
+	}
+	return &nodegroup, nil
+}
+
 func (d DB) DeleteNodeGroup(id string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -295,11 +545,96 @@ func (d DB) DeleteNodeGroup(id string) error {
 }
 
 func (d DB) ListNodeGroup() (*[]NodeGroup, error) {
+	return d.listNodeGroup(bson.M{})
+}
+
+func (d DB) ListNodeGroupByNamespace(namespace string) (*[]NodeGroup, error) {
+	return d.listNodeGroup(namespaceFilter(namespace))
+}
+
+// ListNodeGroupPage - one page of the node groups matching the filter, with a
+// project the node groups shared with all projects are included
+func (d DB) ListNodeGroupPage(f NodeGroupFilter, opts ListOptions) (*api.Page[NodeGroup], error) {
+	if err := validateSort(ngColl, opts.Sort); err != nil {
+		return nil, err
+	}
+
+	filters := bson.A{}
+	if f.ProjectID != "" {
+		filters = append(filters, projectNodeGroupFilter(f.ProjectID))
+	}
+	if f.Namespace != nil {
+		filters = append(filters, namespaceFilter(*f.Namespace))
+	}
+	if f.IsHealthy != nil {
+		filters = append(filters, bson.M{"is_healthy": *f.IsHealthy})
+	}
+	if f.HealthStatus != "" {
+		filters = append(filters, bson.M{"health_status": f.HealthStatus})
+	}
+	if f.Pending != nil {
+		filters = append(filters, flagFilter("pending", *f.Pending))
+	}
+	if f.Cordoned != nil {
+		filters = append(filters, flagFilter("cordoned", *f.Cordoned))
+	}
+	if f.ConfigDrift != nil {
+		filters = append(filters, configDriftFilter(*f.ConfigDrift))
+	}
+	filter := bson.M{}
+	if len(filters) > 0 {
+		filter["$and"] = filters
+	}
+
+	collection := d.client.Database(d.database).Collection(ngColl)
+	return findPage[NodeGroup](collection, filter, opts)
+}
+
+// ListNodeGroupForProjectByNamespace - approved node groups a load test of the
+// project can run on, including cordoned ones which may still run older tests
+func (d DB) ListNodeGroupForProjectByNamespace(projectId string, namespace string) (*[]NodeGroup, error) {
+	return d.listNodeGroup(bson.M{"$and": bson.A{projectNodeGroupFilter(projectId), namespaceFilter(namespace), flagFilter("pending", false)}})
+}
+
+// configDriftFilter - node groups which didn't report to run their desired
+// config, both versions are missing on node groups never configured
+func configDriftFilter(drift bool) bson.M {
+	op := "$eq"
+	if drift {
+		op = "$ne"
+	}
+	return bson.M{"$expr": bson.M{op: bson.A{
+		bson.M{"$ifNull": bson.A{"$config.version", 0}},
+		bson.M{"$ifNull": bson.A{"$applied_config_version", 0}},
+	}}}
+}
+
+// flagFilter - node groups created before a flag existed don't have its field
+func flagFilter(field string, value bool) bson.M {
+	if value {
+		return bson.M{field: true}
+	}
+	return bson.M{field: bson.M{"$ne": true}}
+}
+
+func namespaceFilter(namespace string) bson.M {
+	if namespace == "" {
+		// node groups created before namespaces existed have no namespace field
+		return bson.M{"namespace": bson.M{"$in": bson.A{"", nil}}}
+	}
+	return bson.M{"namespace": namespace}
+}
+
+func projectNodeGroupFilter(projectId string) bson.M {
+	return bson.M{"$or": bson.A{bson.M{"project_id": projectId}, bson.M{"shared": true}}}
+}
+
+func (d DB) listNodeGroup(filter bson.M) (*[]NodeGroup, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	collection := d.client.Database(d.database).Collection(ngColl)
-	cursor, err := collection.Find(ctx, bson.M{})
+	cursor, err := collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -328,8 +663,13 @@ func (d DB) UpdateNodeGroupHealth(nodeGroupId string, isHealthy bool) error {
 	defer cancel()
 
 	collection := d.client.Database(d.database).Collection(ngColl)
+	healthStatus := HealthUnhealthy
+	if isHealthy {
+		healthStatus = HealthHealthy
+	}
 	_, err := collection.UpdateOne(ctx, bson.M{"_id": nodeGroupId}, bson.M{"$set": bson.M{
 		"is_healthy":       isHealthy,
+		"health_status":    healthStatus,
 		"last_health_time": time.Now(),
 	}})
 	if err != nil {
@@ -338,12 +678,36 @@ func (d DB) UpdateNodeGroupHealth(nodeGroupId string, isHealthy bool) error {
 	return nil
 }
 
-func (d DB) PushLoadTestResult(loadTestId string, result NodeUpdates) error {
+// MarkNodeGroupHealth - change the health status of a node group which missed
+// heartbeats, mongo.ErrNoDocuments when a heartbeat arrived since lastHealthCheck
+func (d DB) MarkNodeGroupHealth(nodeGroupId string, healthStatus string, lastHealthCheck time.Time) (*NodeGroup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var nodegroup NodeGroup
+	collection := d.client.Database(d.database).Collection(ngColl)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	filter := bson.M{"_id": nodeGroupId, "last_health_time": lastHealthCheck}
+	update := bson.M{"$set": bson.M{
+		"health_status": healthStatus,
+		"is_healthy":    healthStatus == HealthHealthy,
+	}}
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&nodegroup)
+	if err != nil {
+		return nil, err
+	}
+	return &nodegroup, nil
+}
+
+// PushLoadTestResult - store the results of node heartbeats, returns the
+// results which were not stored before
+func (d DB) PushLoadTestResult(loadTestId string, result NodeUpdates) ([]LoadTestEntry, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	collection := d.client.Database(d.database).Collection(loadTestUpdatesColl)
 
+	entries := []LoadTestEntry{}
 	for _, v := range result {
 		for _, u := range v {
 			nodeUpdate := NodeHeartBeat{}
@@ -353,28 +717,49 @@ func (d DB) PushLoadTestResult(loadTestId string, result NodeUpdates) error {
 			loadTestResultsString, _ := base64.StdEncoding.DecodeString(loadTestResultBase64)
 			loadTestResults := []string{}
 			json.Unmarshal([]byte(loadTestResultsString), &loadTestResults)
-			for _, res := range loadTestResults {
+			batchKey := nodeUpdate.BatchKey()
+			for i, res := range loadTestResults {
 
 				singleResult := bson.M{}
 				json.Unmarshal([]byte(res), &singleResult)
 				singleResult["load_test_id"] = loadTestId
-				singleResult["_id"] = uuid.New().String()
+				singleResult["_id"] = loadTestResultID(loadTestId, batchKey, i)
 				// add single result to db
 				_, err := collection.InsertOne(ctx, singleResult)
 				if err != nil {
-					return err
+					// result was already stored from an earlier delivery of the same batch
+					if mongo.IsDuplicateKeyError(err) {
+						continue
+					}
+					return entries, err
 				}
+				entry := LoadTestEntry{}
+				utils.DeepCopy(singleResult, &entry)
+				entries = append(entries, entry)
 			}
 		}
 	}
-	return nil
+	return entries, nil
+}
+
+// loadTestResultID - derive the result id from the batch it came in so that
+// ingesting the same batch twice doesn't create new results
+func loadTestResultID(loadTestId string, batchKey string, index int) string {
+	if batchKey == "" {
+		return uuid.New().String()
+	}
+	name := fmt.Sprintf("%s/%s/%d", loadTestId, batchKey, index)
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(name)).String()
 }
 
 func (d DB) FetchLoadTestResults(loadTestId string) (map[string]any, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	loadTest, _ := d.GetLoadTestByID(loadTestId)
+	loadTest, err := d.GetLoadTestByID(loadTestId)
+	if err != nil {
+		return nil, err
+	}
 
 	collection := d.client.Database(d.database).Collection(loadTestUpdatesColl)
 	cursor, err := collection.Find(ctx, bson.M{"load_test_id": loadTestId})
@@ -406,6 +791,7 @@ func (d DB) FetchLoadTestResults(loadTestId string) (map[string]any, error) {
 		}
 	}
 
+	successPercent, avgLatencyMs := resultRates(successCount, latencySum, totalRequestCount)
 	return map[string]any{
 		"load_test_id":   loadTestId,
 		"startTime":      loadTest.StartTime,
@@ -415,26 +801,49 @@ func (d DB) FetchLoadTestResults(loadTestId string) (map[string]any, error) {
 		"totalRequests":  totalRequestCount,
 		"successCount":   successCount,
 		"failureCount":   failureCount,
-		"successPercent": (float64)(successCount / totalRequestCount),
-		"avgLatencyMs":   (float64)(latencySum / totalRequestCount),
+		"successPercent": successPercent,
+		"avgLatencyMs":   avgLatencyMs,
 		"topFailures":    failures,
 	}, nil
 }
 
+// resultRates - percentage of successful requests from 0 to 100 and the mean
+// latency, both zero until the first results arrive. Managers before the
+// change returned an integer ratio of 0 or 1 and a truncated latency
+func resultRates(successCount int, latencySum int, totalRequestCount int) (float64, float64) {
+	if totalRequestCount == 0 {
+		return 0, 0
+	}
+	return float64(successCount) * 100 / float64(totalRequestCount), float64(latencySum) / float64(totalRequestCount)
+}
+
 func (d DB) CreateLoadTestSummary(ltsummary LoadTestSummary) (LoadTestSummary, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	ltsummary["created_at"] = time.Now()
-	ltsummary["_id"] = uuid.New().String()
+	// one summary per load test, recomputing it replaces the previous values
+	update := bson.M{}
+	for k, v := range ltsummary {
+		if k != "_id" && k != "created_at" {
+			update[k] = v
+		}
+	}
 
 	collection := d.client.Database(d.database).Collection(ltsummaryColl)
-	_, err := collection.InsertOne(ctx, ltsummary)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetUpsert(true)
+	result := LoadTestSummary{}
+	err := collection.FindOneAndUpdate(ctx, bson.M{"load_test_id": ltsummary["load_test_id"]}, bson.M{
+		"$set": update,
+		"$setOnInsert": bson.M{
+			"_id":        uuid.New().String(),
+			"created_at": time.Now(),
+		},
+	}, opts).Decode(&result)
 	if err != nil {
 		return nil, err
 	}
 
-	return ltsummary, nil
+	return result, nil
 }
 
 func (d DB) GetLoadTestSummaryByID(loadTestId string) (LoadTestSummary, error) {
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"math"
+	"testing"
+)
+
+func TestResultRates(t *testing.T) {
+	tests := []struct {
+		successCount      int
+		latencySum        int
+		totalRequestCount int
+		successPercent    float64
+		avgLatencyMs      float64
+	}{
+		{0, 0, 0, 0, 0},
+		{1, 120, 1, 100, 120},
+		{3, 250, 4, 75, 62.5},
+		{0, 10, 3, 0, 10.0 / 3},
+		{999, 999, 1000, 99.9, 0.999},
+	}
+	for _, tt := range tests {
+		successPercent, avgLatencyMs := resultRates(tt.successCount, tt.latencySum, tt.totalRequestCount)
+		if math.Abs(successPercent-tt.successPercent) > 1e-9 || math.Abs(avgLatencyMs-tt.avgLatencyMs) > 1e-9 {
+			t.Errorf("resultRates(%d, %d, %d) = %v, %v, want %v, %v", tt.successCount, tt.latencySum, tt.totalRequestCount,
+				successPercent, avgLatencyMs, tt.successPercent, tt.avgLatencyMs)
+		}
+	}
+}
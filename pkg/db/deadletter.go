@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (d DB) CreateDeadLetter(deadLetter *DeadLetter) (*DeadLetter, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	deadLetter.ID = uuid.New().String()
+	if deadLetter.ReceivedAt.IsZero() {
+		deadLetter.ReceivedAt = time.Now()
+	}
+
+	collection := d.client.Database(d.database).Collection(deadLetterColl)
+	_, err := collection.InsertOne(ctx, deadLetter)
+	if err != nil {
+		return nil, err
+	}
+
+	return deadLetter, nil
+}
+
+func (d DB) GetDeadLetterByID(id string) (*DeadLetter, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var deadLetter DeadLetter
+	collection := d.client.Database(d.database).Collection(deadLetterColl)
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&deadLetter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deadLetter, nil
+}
+
+func (d DB) UpdateDeadLetter(id string, update bson.M) (*DeadLetter, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var deadLetter DeadLetter
+	collection := d.client.Database(d.database).Collection(deadLetterColl)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": update}, opts).Decode(&deadLetter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deadLetter, nil
+}
+
+func (d DB) DeleteDeadLetter(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(deadLetterColl)
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// PurgeDeadLetters - delete all dead letters, returns the number of deleted entries
+func (d DB) PurgeDeadLetters() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(deadLetterColl)
+	result, err := collection.DeleteMany(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+func (d DB) ListDeadLetter() (*[]DeadLetter, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(deadLetterColl)
+	opts := options.Find().SetSort(bson.M{"received_at": -1})
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deadLetters := []DeadLetter{}
+
+	for cursor.Next(ctx) {
+		var deadLetter DeadLetter
+		err := cursor.Decode(&deadLetter)
+		if err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return &deadLetters, nil
+}
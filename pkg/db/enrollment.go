@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (d DB) CreateEnrollmentToken(token *EnrollmentToken) (*EnrollmentToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token.ID = uuid.New().String()
+	token.CreatedAt = time.Now()
+
+	collection := d.client.Database(d.database).Collection(enrollmentColl)
+	_, err := collection.InsertOne(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+func (d DB) GetEnrollmentTokenByID(id string) (*EnrollmentToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var token EnrollmentToken
+	collection := d.client.Database(d.database).Collection(enrollmentColl)
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (d DB) GetEnrollmentTokenByHash(tokenHash string) (*EnrollmentToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var token EnrollmentToken
+	collection := d.client.Database(d.database).Collection(enrollmentColl)
+	err := collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (d DB) UpdateEnrollmentToken(id string, update bson.M) (*EnrollmentToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var token EnrollmentToken
+	collection := d.client.Database(d.database).Collection(enrollmentColl)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, bson.M{"$set": update}, opts).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// UseEnrollmentToken - count a node group registered with the token
+func (d DB) UseEnrollmentToken(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(enrollmentColl)
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"use_count": 1}})
+	return err
+}
+
+func (d DB) ListEnrollmentToken() (*[]EnrollmentToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(enrollmentColl)
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tokens := []EnrollmentToken{}
+	for cursor.Next(ctx) {
+		var token EnrollmentToken
+		if err := cursor.Decode(&token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return &tokens, nil
+}
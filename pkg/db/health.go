@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (d DB) CreateHealthEvent(event *HealthEvent) (*HealthEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	event.ID = uuid.New().String()
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	collection := d.client.Database(d.database).Collection(healthEventColl)
+	_, err := collection.InsertOne(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// ListHealthEvent - health transitions of a node group between from and to,
+// oldest first
+func (d DB) ListHealthEvent(nodeGroupId string, from time.Time, to time.Time) (*[]HealthEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(healthEventColl)
+	filter := bson.M{"node_group_id": nodeGroupId, "timestamp": bson.M{"$gte": from, "$lt": to}}
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: 1}})
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []HealthEvent{}
+	for cursor.Next(ctx) {
+		var event HealthEvent
+		if err := cursor.Decode(&event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return &events, nil
+}
+
+// GetHealthEventBefore - the last health transition of a node group before t,
+// mongo.ErrNoDocuments when there was none
+func (d DB) GetHealthEventBefore(nodeGroupId string, t time.Time) (*HealthEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var event HealthEvent
+	collection := d.client.Database(d.database).Collection(healthEventColl)
+	opts := options.FindOne().SetSort(bson.D{{Key: "timestamp", Value: -1}})
+	err := collection.FindOne(ctx, bson.M{"node_group_id": nodeGroupId, "timestamp": bson.M{"$lt": t}}, opts).Decode(&event)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (d DB) DeleteHealthEventsByNodeGroup(nodeGroupId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := d.client.Database(d.database).Collection(healthEventColl).DeleteMany(ctx, bson.M{"node_group_id": nodeGroupId})
+	return err
+}
+
+// ListLoadTestOverlapping - load tests the node group took part in which
+// started before to and had not ended at from, load tests without an end time
+// are filtered by the caller. Load tests from before node groups were recorded
+// ran on every node group of their project in the namespace
+func (d DB) ListLoadTestOverlapping(ng NodeGroup, from time.Time, to time.Time) (*[]LoadTest, error) {
+	unbound := bson.M{
+		"node_groups": bson.M{"$exists": false},
+		"namespace":   ng.Namespace,
+	}
+	if !ng.Shared {
+		unbound["project_id"] = ng.ProjectID
+	}
+	filter := bson.M{
+		// queued and cancelled load tests never ran
+		"status":     bson.M{"$in": bson.A{"running", "stopping", "complete", "stopped", "failed"}},
+		"start_time": bson.M{"$lt": to},
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"end_time": bson.M{"$gt": from}},
+				bson.M{"end_time": time.Time{}},
+				bson.M{"end_time": bson.M{"$exists": false}},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"node_groups": ng.ID},
+				unbound,
+			}},
+		},
+	}
+	return d.listLoadTest(filter)
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvalidArgument - wrapped by errors caused by bad arguments of the caller
+var ErrInvalidArgument = errors.New("invalid argument")
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+// ListOptions - offset pagination and sorting, Sort is a field name with an
+// optional "-" prefix for descending order
+type ListOptions struct {
+	Offset int64
+	Limit  int64
+	Sort   string
+}
+
+// sortFields - fields list endpoints can be sorted by
+var sortFields = map[string][]string{
+	loadtestColl: {"start_time", "end_time", "tps", "duration", "status", "created_by", "description", "priority", "queued_at"},
+	ngColl:       {"_id", "topic", "namespace", "is_healthy", "health_status", "last_health_time"},
+	userColl:     {"name", "email", "role", "created_at"},
+}
+
+// validateSort - check that a sort given in a request is allowed for the collection
+func validateSort(coll string, sort string) error {
+	field := strings.TrimPrefix(sort, "-")
+	if field == "" {
+		return nil
+	}
+	for _, f := range sortFields[coll] {
+		if f == field {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w can't sort by %s, use one of %s", ErrInvalidArgument, field, strings.Join(sortFields[coll], ", "))
+}
+
+func findPage[T any](collection *mongo.Collection, filter bson.M, opts ListOptions) (*api.Page[T], error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultPageLimit
+	}
+	if opts.Limit > MaxPageLimit {
+		opts.Limit = MaxPageLimit
+	}
+	if opts.Offset < 0 {
+		opts.Offset = 0
+	}
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	findOpts := options.Find().SetSkip(opts.Offset).SetLimit(opts.Limit)
+	if opts.Sort != "" {
+		order := 1
+		if strings.HasPrefix(opts.Sort, "-") {
+			order = -1
+		}
+		field := strings.TrimPrefix(opts.Sort, "-")
+		sort := bson.D{{Key: field, Value: order}}
+		// _id as tie breaker keeps pages stable, it is unique by itself
+		if field != "_id" {
+			sort = append(sort, bson.E{Key: "_id", Value: 1})
+		}
+		findOpts.SetSort(sort)
+	}
+
+	cursor, err := collection.Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []T{}
+
+	for cursor.Next(ctx) {
+		var item T
+		err := cursor.Decode(&item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return &api.Page[T]{
+		Items:  items,
+		Total:  total,
+		Offset: opts.Offset,
+		Limit:  opts.Limit,
+	}, nil
+}
@@ -1,68 +1,61 @@
 package db
 
 import (
-	"time"
-
+	"github.com/mridulganga/dlt-manager/pkg/api"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
-type Data map[string]any
-type NodeUpdates map[string][]Data
+// the models are defined in pkg/api so that api clients can use them without
+// depending on the database
+
+type (
+	Data              = api.Data
+	NodeUpdates       = api.NodeUpdates
+	User              = api.User
+	APIKey            = api.APIKey
+	EnrollmentToken   = api.EnrollmentToken
+	Session           = api.Session
+	LoadTest          = api.LoadTest
+	NodeGroup         = api.NodeGroup
+	NodeGroupConfig   = api.NodeGroupConfig
+	NodeGroupCapacity = api.NodeGroupCapacity
+	HealthEvent       = api.HealthEvent
+	NGHeartbeat       = api.NGHeartbeat
+	NodeHeartBeat     = api.NodeHeartBeat
+	Node              = api.Node
+	NodeEvent         = api.NodeEvent
+	LoadTestEntry     = api.LoadTestEntry
+	DeadLetter        = api.DeadLetter
+	Change            = api.Change
+	AuditEntry        = api.AuditEntry
+	AuditFilter       = api.AuditFilter
+	ProjectQuota      = api.ProjectQuota
+	Project           = api.Project
+	LoadTestFilter    = api.LoadTestFilter
+	NodeGroupFilter   = api.NodeGroupFilter
+)
 
-type User struct {
-	ID        string    `bson:"_id" json:"_id"`
-	Name      string    `bson:"name" json:"name"`
-	Email     string    `bson:"email" json:"email"`
-	Password  string    `bson:"password" json:"password"`
-	CreatedAt time.Time `bson:"created_at" json:"created_at"`
-}
+const (
+	RoleViewer   = api.RoleViewer
+	RoleOperator = api.RoleOperator
+	RoleAdmin    = api.RoleAdmin
 
-type LoadTest struct {
-	ID          string    `bson:"_id" json:"_id,omitempty"`
-	Description string    `bson:"description" json:"description"`
-	TPS         float64   `bson:"tps" json:"tps"`
-	Duration    int       `bson:"duration" json:"duration"`
-	Logic       string    `bson:"logic" json:"logic"`
-	CreatedBy   string    `bson:"created_by" json:"created_by"`
-	StartTime   time.Time `bson:"start_time" json:"start_time"`
-	EndTime     time.Time `bson:"end_time" json:"end_time"`
-	Status      string    `bson:"status" json:"status"`
-}
+	HealthHealthy   = api.HealthHealthy
+	HealthStale     = api.HealthStale
+	HealthUnhealthy = api.HealthUnhealthy
 
-type NodeGroup struct {
-	ID              string    `bson:"_id" json:"_id"`
-	Nodes           []string  `bson:"nodes"`
-	Topic           string    `bson:"topic"`
-	IsHealthy       bool      `bson:"is_healthy"`
-	LastHealthCheck time.Time `bson:"last_health_time"`
-}
+	NodeEventJoined = api.NodeEventJoined
+	NodeEventLeft   = api.NodeEventLeft
+	NodeEventStatus = api.NodeEventStatus
+	NodeStatusLeft  = api.NodeStatusLeft
 
-type LoadTestSummary bson.M
-
-type NGHeartbeat struct {
-	Action           string   `json:"action"`
-	NodeGroupStatus  string   `json:"ng_status"`
-	NodeGroupID      string   `json:"ng_id"`
-	Nodes            []string `json:"nodes"`
-	IsLoadTestActive bool     `json:"isLoadTestActive"`
-	Timestamp        string   `json:"timestamp"`
-	NodeUpdates      string   `json:"node_updates,omitempty"`
-	LoadTestId       string   `json:"load_test_id,omitempty"`
-}
+	ActorTypeUser   = api.ActorTypeUser
+	ActorTypeAPIKey = api.ActorTypeAPIKey
+	ActorTypeSystem = api.ActorTypeSystem
+)
 
-type NodeHeartBeat struct {
-	Action          string `json:"action"`
-	IsTestActive    string `json:"isTestActive"`
-	LoadTestID      string `json:"load_test_id"`
-	LoadTestResults string `json:"load_test_results"`
-	NodeID          string `json:"node_id"`
-	NodeStatus      string `json:"node_status"`
-	Timestamp       string `json:"timestamp"`
+func IsValidRole(role string) bool {
+	return api.IsValidRole(role)
 }
 
-type LoadTestEntry struct {
-	IsSuccess  string `bson:"isSuccess"`
-	LatencyMs  string `bson:"latencyMs"`
-	Response   string `bson:"response"`
-	StatusCode string `bson:"statusCode"`
-}
+type LoadTestSummary bson.M
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// nodeKey - node ids are only unique within their node group
+func nodeKey(nodeGroupId string, nodeId string) string {
+	return nodeGroupId + "/" + nodeId
+}
+
+// UpsertNode - store what a heartbeat reported about a node, the node is
+// created when it is new to its node group
+func (d DB) UpsertNode(node *Node) (*Node, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	node.ID = nodeKey(node.NodeGroupID, node.NodeID)
+	update := bson.M{
+		"$set": bson.M{
+			"node_id":       node.NodeID,
+			"node_group_id": node.NodeGroupID,
+			"status":        node.Status,
+			"version":       node.Version,
+			"capacity":      node.Capacity,
+			"load_test_id":  node.LoadTestID,
+			"last_seen":     node.LastSeen,
+			"joined_at":     node.JoinedAt,
+		},
+		"$unset": bson.M{"left_at": ""},
+	}
+
+	var result Node
+	collection := d.client.Database(d.database).Collection(nodeColl)
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": node.ID}, update, opts).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// MarkNodeLeft - the node is no longer listed by its node group
+func (d DB) MarkNodeLeft(nodeGroupId string, nodeId string) (*Node, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var result Node
+	collection := d.client.Database(d.database).Collection(nodeColl)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	update := bson.M{"$set": bson.M{"status": NodeStatusLeft, "load_test_id": "", "left_at": time.Now()}}
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": nodeKey(nodeGroupId, nodeId)}, update, opts).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (d DB) ListNodeByNodeGroup(nodeGroupId string) (*[]Node, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(nodeColl)
+	opts := options.Find().SetSort(bson.D{{Key: "node_id", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"node_group_id": nodeGroupId}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	nodes := []Node{}
+	for cursor.Next(ctx) {
+		var node Node
+		if err := cursor.Decode(&node); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return &nodes, nil
+}
+
+// DeleteNodesByNodeGroup - remove the nodes and node history of a node group
+func (d DB) DeleteNodesByNodeGroup(nodeGroupId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := d.client.Database(d.database).Collection(nodeColl).DeleteMany(ctx, bson.M{"node_group_id": nodeGroupId}); err != nil {
+		return err
+	}
+	_, err := d.client.Database(d.database).Collection(nodeEventColl).DeleteMany(ctx, bson.M{"node_group_id": nodeGroupId})
+	return err
+}
+
+func (d DB) CreateNodeEvent(event *NodeEvent) (*NodeEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	event.ID = uuid.New().String()
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	collection := d.client.Database(d.database).Collection(nodeEventColl)
+	_, err := collection.InsertOne(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// ListNodeEvent - latest events of the nodes of a node group, newest first
+func (d DB) ListNodeEvent(nodeGroupId string, limit int64) (*[]NodeEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(nodeEventColl)
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}).SetLimit(limit)
+	cursor, err := collection.Find(ctx, bson.M{"node_group_id": nodeGroupId}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []NodeEvent{}
+	for cursor.Next(ctx) {
+		var event NodeEvent
+		if err := cursor.Decode(&event); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return &events, nil
+}
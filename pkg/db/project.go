@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (d DB) CreateProject(project *Project) (*Project, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	project.ID = uuid.New().String()
+	project.CreatedAt = time.Now()
+	if project.Members == nil {
+		project.Members = []string{}
+	}
+
+	collection := d.client.Database(d.database).Collection(projectColl)
+	_, err := collection.InsertOne(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (d DB) GetProjectByID(id string) (*Project, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var project Project
+	collection := d.client.Database(d.database).Collection(projectColl)
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&project)
+	if err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+func (d DB) UpdateProject(id string, update bson.M) (*Project, error) {
+	return d.updateProject(id, bson.M{"$set": update})
+}
+
+func (d DB) AddProjectMember(id string, userId string) (*Project, error) {
+	return d.updateProject(id, bson.M{"$addToSet": bson.M{"members": userId}})
+}
+
+func (d DB) RemoveProjectMember(id string, userId string) (*Project, error) {
+	return d.updateProject(id, bson.M{"$pull": bson.M{"members": userId}})
+}
+
+func (d DB) updateProject(id string, update bson.M) (*Project, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var project Project
+	collection := d.client.Database(d.database).Collection(projectColl)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&project)
+	if err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+func (d DB) DeleteProject(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(projectColl)
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CountProjectResources - number of node groups and load tests the project
+// still owns
+func (d DB) CountProjectResources(id string) (int64, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"project_id": id}
+	nodeGroups, err := d.client.Database(d.database).Collection(ngColl).CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, 0, err
+	}
+	loadtests, err := d.client.Database(d.database).Collection(loadtestColl).CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return nodeGroups, loadtests, nil
+}
+
+func (d DB) ListProject() (*[]Project, error) {
+	return d.listProject(bson.M{})
+}
+
+func (d DB) ListProjectByMember(userId string) (*[]Project, error) {
+	return d.listProject(bson.M{"members": userId})
+}
+
+func (d DB) listProject(filter bson.M) (*[]Project, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(projectColl)
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	projects := []Project{}
+
+	for cursor.Next(ctx) {
+		var project Project
+		err := cursor.Decode(&project)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return &projects, nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (d DB) CreateSession(session *Session) (*Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	session.ID = uuid.New().String()
+	session.CreatedAt = time.Now()
+
+	collection := d.client.Database(d.database).Collection(sessionColl)
+	_, err := collection.InsertOne(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func (d DB) GetSessionByID(id string) (*Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var session Session
+	collection := d.client.Database(d.database).Collection(sessionColl)
+	err := collection.FindOne(ctx, bson.M{"_id": id}).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (d DB) DeleteSession(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(sessionColl)
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteUserSessions - log a user out everywhere
+func (d DB) DeleteUserSessions(userId string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	collection := d.client.Database(d.database).Collection(sessionColl)
+	_, err := collection.DeleteMany(ctx, bson.M{"user_id": userId})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,205 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"github.com/mridulganga/dlt-manager/pkg/live"
+	"github.com/mridulganga/dlt-manager/pkg/transport"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// detect node groups which stopped sending heartbeats, heartbeats only ever
+// make a node group healthy again
+
+type Monitor struct {
+	d   *db.DB
+	au  *audit.Auditor
+	hub *live.Hub
+	m   transport.Transport
+
+	// node groups are expected to send a heartbeat every interval, they become
+	// stale after staleAfter and unhealthy after unhealthyAfter missed ones
+	interval       time.Duration
+	staleAfter     int
+	unhealthyAfter int
+}
+
+func NewMonitor(database *db.DB, auditor *audit.Auditor, hub *live.Hub, t transport.Transport, interval time.Duration, staleAfter int, unhealthyAfter int) *Monitor {
+	return &Monitor{
+		d:              database,
+		au:             auditor,
+		hub:            hub,
+		m:              t,
+		interval:       interval,
+		staleAfter:     staleAfter,
+		unhealthyAfter: unhealthyAfter,
+	}
+}
+
+// Run - check the node groups every interval until ctx is done
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check()
+		}
+	}
+}
+
+// Check - move node groups which missed heartbeats to stale or unhealthy
+func (m *Monitor) Check() {
+	nodegroups, err := m.d.ListNodeGroup()
+	if err != nil {
+		logrus.Errorf("error while ListNodeGroup %v", err.Error())
+		return
+	}
+	for _, ng := range *nodegroups {
+		// node groups which never sent a heartbeat have nothing to miss
+		if ng.LastHealthCheck.IsZero() {
+			continue
+		}
+		status := m.status(time.Since(ng.LastHealthCheck), m.heartbeatInterval(ng))
+		if severity[status] <= severity[ng.Health()] {
+			continue
+		}
+		m.transition(ng, status)
+	}
+}
+
+// severity - the monitor only moves node groups towards unhealthy
+var severity = map[string]int{
+	db.HealthHealthy:   0,
+	db.HealthStale:     1,
+	db.HealthUnhealthy: 2,
+}
+
+// heartbeatInterval - node groups running a config with their own heartbeat
+// interval are expected at that interval
+func (m *Monitor) heartbeatInterval(ng db.NodeGroup) time.Duration {
+	if ng.Config.HeartbeatInterval > 0 && ng.Config.Version > 0 && ng.ConfigInSync() {
+		return time.Duration(ng.Config.HeartbeatInterval) * time.Second
+	}
+	return m.interval
+}
+
+func (m *Monitor) status(sinceHeartbeat time.Duration, interval time.Duration) string {
+	missed := int(sinceHeartbeat / interval)
+	switch {
+	case missed >= m.unhealthyAfter:
+		return db.HealthUnhealthy
+	case missed >= m.staleAfter:
+		return db.HealthStale
+	}
+	return db.HealthHealthy
+}
+
+func (m *Monitor) transition(ng db.NodeGroup, status string) {
+	updated, err := m.d.MarkNodeGroupHealth(ng.ID, status, ng.LastHealthCheck)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// a heartbeat arrived in the meantime
+		return
+	}
+	if err != nil {
+		logrus.Errorf("error while MarkNodeGroupHealth %v", err.Error())
+		return
+	}
+	logrus.Warnf("node group %s is %s, last heartbeat %s", ng.ID, status, ng.LastHealthCheck.Format(time.RFC3339))
+	m.au.System(audit.Health, audit.NodeGroup, ng.ID,
+		map[string]any{"is_healthy": ng.IsHealthy, "health_status": ng.Health()},
+		map[string]any{"is_healthy": updated.IsHealthy, "health_status": status})
+	if _, err := m.d.CreateHealthEvent(&db.HealthEvent{NodeGroupID: ng.ID, From: ng.Health(), To: status}); err != nil {
+		logrus.Errorf("error while CreateHealthEvent %v", err.Error())
+	}
+
+	loadtests, err := m.d.ListRunningLoadTestForNodeGroup(ng)
+	if err != nil {
+		logrus.Errorf("error while ListRunningLoadTestForNodeGroup %v", err.Error())
+		return
+	}
+	for _, lt := range *loadtests {
+		m.hub.NodeGroupHealth(lt.ID, ng.ID, status)
+		if status == db.HealthUnhealthy {
+			m.lostNodeGroup(lt, ng)
+		}
+	}
+}
+
+// lostNodeGroup - every node group of a load test runs the whole load test, so
+// the load test carries on while another of its node groups is still healthy
+// and fails otherwise. Cordoning only keeps new load tests off a node group,
+// a cordoned node group the load test is bound to is still running it. The
+// lost node group is done with the load test so that the remaining ones
+// complete it
+func (m *Monitor) lostNodeGroup(lt db.LoadTest, lost db.NodeGroup) {
+	nodegroups, err := m.d.ListNodeGroupForProjectByNamespace(lt.ProjectID, lt.Namespace)
+	if err != nil {
+		logrus.Errorf("error while ListNodeGroupForProjectByNamespace %v", err.Error())
+		return
+	}
+	if hasRemainingNodeGroup(lt, lost, *nodegroups) {
+		logrus.Warnf("load test %s continues without node group %s", lt.ID, lost.ID)
+		if _, err := m.d.MarkLoadTestNodeGroupDone(lt.ID, lost.ID); err != nil {
+			logrus.Errorf("error while MarkLoadTestNodeGroupDone %v", err.Error())
+		}
+		return
+	}
+
+	updated, err := m.d.UpdateLoadTestIfStatus(lt.ID, lt.Status, bson.M{"status": lt.EndStatus("failed"), "end_time": time.Now()})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return
+	}
+	if err != nil {
+		logrus.Errorf("error while failing load test %s %v", lt.ID, err.Error())
+		return
+	}
+	logrus.Warnf("load test %s %s, no healthy node group left", lt.ID, updated.Status)
+	m.au.System(audit.Fail, audit.LoadTest, lt.ID, lt, updated)
+	m.hub.Status(lt.ID, updated.Status)
+
+	// node groups which come back must not carry on with the failed test
+	for _, ng := range *nodegroups {
+		if !lt.RunsOn(ng.ID) {
+			continue
+		}
+		m.m.Publish(ng.Topic, map[string]any{
+			"action":       "stop_loadtest",
+			"load_test_id": lt.ID,
+		})
+	}
+
+	summary, err := m.d.FetchLoadTestResults(lt.ID)
+	if err != nil {
+		logrus.Errorf("error while FetchLoadTestResults %v", err.Error())
+		return
+	}
+	if _, err := m.d.CreateLoadTestSummary(summary); err != nil {
+		logrus.Errorf("error while CreateLoadTestSummary %v", err.Error())
+	}
+	m.hub.Complete(lt.ID, summary)
+}
+
+// hasRemainingNodeGroup - whether a healthy node group other than the lost one
+// is running the load test
+func hasRemainingNodeGroup(lt db.LoadTest, lost db.NodeGroup, nodegroups []db.NodeGroup) bool {
+	for _, ng := range nodegroups {
+		if ng.ID == lost.ID || ng.Health() != db.HealthHealthy {
+			continue
+		}
+		// unbound load tests only started on node groups which weren't cordoned
+		if slices.Contains(lt.NodeGroups, ng.ID) || len(lt.NodeGroups) == 0 && !ng.Cordoned {
+			return true
+		}
+	}
+	return false
+}
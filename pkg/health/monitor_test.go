@@ -0,0 +1,35 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/mridulganga/dlt-manager/pkg/db"
+)
+
+func TestHasRemainingNodeGroup(t *testing.T) {
+	lost := db.NodeGroup{ID: "lost", HealthStatus: db.HealthUnhealthy}
+	healthy := db.NodeGroup{ID: "healthy", HealthStatus: db.HealthHealthy}
+	stale := db.NodeGroup{ID: "stale", HealthStatus: db.HealthStale}
+	cordoned := db.NodeGroup{ID: "cordoned", HealthStatus: db.HealthHealthy, Cordoned: true}
+
+	tests := []struct {
+		name       string
+		bound      []string
+		nodegroups []db.NodeGroup
+		want       bool
+	}{
+		{"only the lost group", []string{"lost"}, []db.NodeGroup{lost}, false},
+		{"bound healthy group", []string{"lost", "healthy"}, []db.NodeGroup{lost, healthy}, true},
+		{"healthy group not bound", []string{"lost"}, []db.NodeGroup{lost, healthy}, false},
+		{"bound stale group", []string{"lost", "stale"}, []db.NodeGroup{lost, stale}, false},
+		{"bound cordoned group keeps running", []string{"lost", "cordoned"}, []db.NodeGroup{lost, cordoned}, true},
+		{"unbound on a healthy group", nil, []db.NodeGroup{lost, healthy}, true},
+		{"unbound never started on a cordoned group", nil, []db.NodeGroup{lost, cordoned}, false},
+	}
+	for _, tt := range tests {
+		lt := db.LoadTest{ID: "lt", NodeGroups: tt.bound}
+		if got := hasRemainingNodeGroup(lt, lost, tt.nodegroups); got != tt.want {
+			t.Errorf("%s: hasRemainingNodeGroup() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
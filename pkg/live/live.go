@@ -0,0 +1,148 @@
+package live
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+)
+
+// push live updates of running load tests to the clients following them
+
+const (
+	EventStats   = api.EventStats
+	EventStatus  = api.EventStatus
+	EventSummary = api.EventSummary
+	EventHealth  = api.EventHealth
+)
+
+// the events are defined in pkg/api so that api clients can decode them
+type (
+	Event           = api.Event
+	StatusChange    = api.StatusChange
+	HealthChange    = api.HealthChange
+	NodeGroupStatus = api.NodeGroupStatus
+	Percentiles     = api.Percentiles
+	Stats           = api.Stats
+)
+
+// subscriberBuffer - events a slow subscriber can fall behind before stats
+// events are dropped for it
+const subscriberBuffer = 64
+
+type Hub struct {
+	mu          sync.Mutex
+	trackers    map[string]*tracker
+	subscribers map[string]map[chan Event]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		trackers:    map[string]*tracker{},
+		subscribers: map[string]map[chan Event]struct{}{},
+	}
+}
+
+// Subscribe - events of a load test, call the returned func to stop
+// receiving them
+func (h *Hub) Subscribe(loadTestId string) (<-chan Event, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	if h.subscribers[loadTestId] == nil {
+		h.subscribers[loadTestId] = map[chan Event]struct{}{}
+	}
+	h.subscribers[loadTestId][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[loadTestId], ch)
+		if len(h.subscribers[loadTestId]) == 0 {
+			delete(h.subscribers, loadTestId)
+		}
+	}
+}
+
+// Snapshot - current stats of a load test, false when nothing was received
+// for it yet
+func (h *Hub) Snapshot(loadTestId string) (Stats, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	t, ok := h.trackers[loadTestId]
+	if !ok {
+		return Stats{}, false
+	}
+	return t.snapshot(), true
+}
+
+// Record - a heartbeat of a node group running the load test was processed
+// with the results which were new in it
+func (h *Hub) Record(loadTestId string, ngId string, isHealthy bool, nodes int, entries []db.LoadTestEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t, ok := h.trackers[loadTestId]
+	if !ok {
+		t = newTracker(loadTestId)
+		h.trackers[loadTestId] = t
+	}
+	now := time.Now()
+	t.nodeGroup(ngId, NodeGroupStatus{IsHealthy: isHealthy, Nodes: nodes, LastHeartbeat: now})
+	t.add(now, entries)
+	h.publish(Event{Type: EventStats, LoadTestID: loadTestId, Data: t.snapshot()})
+}
+
+// Status - the status of a load test changed
+func (h *Hub) Status(loadTestId string, status string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.publish(Event{Type: EventStatus, LoadTestID: loadTestId, Data: StatusChange{Status: status}})
+}
+
+// NodeGroupHealth - the health of a node group running the load test changed
+// without a heartbeat, ie. it stopped sending them
+func (h *Hub) NodeGroupHealth(loadTestId string, ngId string, healthStatus string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if t, ok := h.trackers[loadTestId]; ok {
+		if status, ok := t.stats.NodeGroups[ngId]; ok {
+			status.IsHealthy = false
+			t.stats.NodeGroups[ngId] = status
+		}
+	}
+	h.publish(Event{Type: EventHealth, LoadTestID: loadTestId, Data: HealthChange{NodeGroupID: ngId, HealthStatus: healthStatus}})
+}
+
+// Complete - the load test finished, send its summary and forget its stats
+func (h *Hub) Complete(loadTestId string, summary any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.publish(Event{Type: EventSummary, LoadTestID: loadTestId, Data: summary})
+	delete(h.trackers, loadTestId)
+}
+
+// publish - never blocks the processor, a subscriber which fell behind misses
+// stats events but older events make room for status and summary events
+func (h *Hub) publish(e Event) {
+	for ch := range h.subscribers[e.LoadTestID] {
+		select {
+		case ch <- e:
+			continue
+		default:
+		}
+		if e.Type == EventStats {
+			continue
+		}
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package live
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/db"
+)
+
+// rateWindow - current tps and error rate are computed over the results
+// received in this window
+const rateWindow = 10 * time.Second
+
+// latency histogram buckets grow by bucketFactor from 1ms, percentiles are
+// reported as the upper bound of their bucket so they are within 5%
+const (
+	bucketFactor = 1.05
+	bucketCount  = 300
+)
+
+type window struct {
+	at       time.Time
+	requests int64
+	failures int64
+}
+
+// tracker - aggregates the results of one load test as they arrive so that
+// stats never need the stored results to be scanned
+type tracker struct {
+	stats   Stats
+	buckets [bucketCount]int64
+	recent  []window
+	started time.Time
+}
+
+func newTracker(loadTestId string) *tracker {
+	return &tracker{
+		stats: Stats{
+			LoadTestID: loadTestId,
+			NodeGroups: map[string]NodeGroupStatus{},
+		},
+		started: time.Now(),
+	}
+}
+
+func (t *tracker) nodeGroup(ngId string, status NodeGroupStatus) {
+	t.stats.NodeGroups[ngId] = status
+	t.stats.UpdatedAt = status.LastHeartbeat
+}
+
+func (t *tracker) add(now time.Time, entries []db.LoadTestEntry) {
+	w := window{at: now}
+	for _, entry := range entries {
+		w.requests++
+		if entry.IsSuccess == "true" {
+			t.stats.SuccessCount++
+		} else {
+			w.failures++
+			t.stats.FailureCount++
+		}
+		latencyMs, _ := strconv.ParseFloat(entry.LatencyMs, 64)
+		t.buckets[bucketOf(latencyMs)]++
+		if latencyMs > t.stats.LatencyMs.Max {
+			t.stats.LatencyMs.Max = latencyMs
+		}
+	}
+	t.stats.TotalRequests += w.requests
+	t.recent = append(t.recent, w)
+	t.stats.UpdatedAt = now
+
+	// drop results which left the window
+	for len(t.recent) > 0 && now.Sub(t.recent[0].at) > rateWindow {
+		t.recent = t.recent[1:]
+	}
+	requests, failures := int64(0), int64(0)
+	for _, r := range t.recent {
+		requests += r.requests
+		failures += r.failures
+	}
+	// until the window is full the rate is over the time since the first
+	// results, at least a second as results arrive in batches
+	elapsed := rateWindow
+	if since := now.Sub(t.started); since < rateWindow {
+		elapsed = max(since, time.Second)
+	}
+	t.stats.CurrentTPS = float64(requests) / elapsed.Seconds()
+	t.stats.ErrorRate = 0
+	if requests > 0 {
+		t.stats.ErrorRate = float64(failures) / float64(requests)
+	}
+
+	t.stats.LatencyMs.P50 = t.percentile(0.50)
+	t.stats.LatencyMs.P90 = t.percentile(0.90)
+	t.stats.LatencyMs.P95 = t.percentile(0.95)
+	t.stats.LatencyMs.P99 = t.percentile(0.99)
+}
+
+func (t *tracker) percentile(p float64) float64 {
+	if t.stats.TotalRequests == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(p * float64(t.stats.TotalRequests)))
+	seen := int64(0)
+	for i, count := range t.buckets {
+		seen += count
+		if seen >= rank {
+			return math.Min(bucketUpperBound(i), t.stats.LatencyMs.Max)
+		}
+	}
+	return t.stats.LatencyMs.Max
+}
+
+// snapshot - copy of the stats which can be handed to subscribers
+func (t *tracker) snapshot() Stats {
+	s := t.stats
+	s.NodeGroups = make(map[string]NodeGroupStatus, len(t.stats.NodeGroups))
+	for id, status := range t.stats.NodeGroups {
+		s.NodeGroups[id] = status
+	}
+	return s
+}
+
+func bucketOf(latencyMs float64) int {
+	if latencyMs <= 1 {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(latencyMs) / math.Log(bucketFactor)))
+	if i >= bucketCount {
+		return bucketCount - 1
+	}
+	return i
+}
+
+func bucketUpperBound(i int) float64 {
+	return math.Pow(bucketFactor, float64(i))
+}
@@ -0,0 +1,132 @@
+package live
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/db"
+)
+
+func entries(latencies []int, failures int) []db.LoadTestEntry {
+	result := []db.LoadTestEntry{}
+	for i, latency := range latencies {
+		success := "true"
+		if i < failures {
+			success = "false"
+		}
+		result = append(result, db.LoadTestEntry{IsSuccess: success, LatencyMs: strconv.Itoa(latency)})
+	}
+	return result
+}
+
+// within - percentiles are the upper bound of their bucket
+func within(got float64, want float64) bool {
+	return got >= want && got <= want*bucketFactor
+}
+
+func TestTrackerPercentiles(t *testing.T) {
+	latencies := []int{}
+	for i := 1; i <= 1000; i++ {
+		latencies = append(latencies, i)
+	}
+	tr := newTracker("lt")
+	tr.add(time.Now(), entries(latencies, 0))
+
+	got := tr.stats.LatencyMs
+	want := Percentiles{P50: 500, P90: 900, P95: 950, P99: 990, Max: 1000}
+	if !within(got.P50, want.P50) || !within(got.P90, want.P90) || !within(got.P95, want.P95) || !within(got.P99, want.P99) {
+		t.Errorf("percentiles %+v, want about %+v", got, want)
+	}
+	if got.Max != want.Max {
+		t.Errorf("max %v, want %v", got.Max, want.Max)
+	}
+}
+
+func TestTrackerPercentilesNeverExceedMax(t *testing.T) {
+	tr := newTracker("lt")
+	tr.add(time.Now(), entries([]int{101, 101, 101}, 0))
+
+	if p := tr.stats.LatencyMs; p.P50 != 101 || p.P99 != 101 {
+		t.Errorf("percentiles %+v of identical latencies, want 101", p)
+	}
+}
+
+func TestTrackerWithoutResults(t *testing.T) {
+	tr := newTracker("lt")
+	if p := tr.percentile(0.5); p != 0 {
+		t.Errorf("percentile without results %v", p)
+	}
+	tr.add(time.Now(), nil)
+	if tr.stats.CurrentTPS != 0 || tr.stats.ErrorRate != 0 {
+		t.Errorf("stats without results %+v", tr.stats)
+	}
+}
+
+func TestTrackerRates(t *testing.T) {
+	tr := newTracker("lt")
+	start := tr.started
+
+	// 100 results in the first two seconds, a quarter failed
+	tr.add(start.Add(2*time.Second), entries(make([]int, 100), 25))
+	if tr.stats.CurrentTPS != 50 {
+		t.Errorf("tps %v, want 50", tr.stats.CurrentTPS)
+	}
+	if tr.stats.ErrorRate != 0.25 {
+		t.Errorf("error rate %v, want 0.25", tr.stats.ErrorRate)
+	}
+
+	// the first batch leaves the window, only the second one counts
+	tr.add(start.Add(20*time.Second), entries(make([]int, 30), 0))
+	if tr.stats.CurrentTPS != 3 {
+		t.Errorf("tps %v, want 3 over the window", tr.stats.CurrentTPS)
+	}
+	if tr.stats.ErrorRate != 0 {
+		t.Errorf("error rate %v, want 0", tr.stats.ErrorRate)
+	}
+	if tr.stats.TotalRequests != 130 || tr.stats.FailureCount != 25 || tr.stats.SuccessCount != 105 {
+		t.Errorf("totals %+v", tr.stats)
+	}
+}
+
+func TestBucketOf(t *testing.T) {
+	if b := bucketOf(0); b != 0 {
+		t.Errorf("bucket of 0ms %d", b)
+	}
+	if b := bucketOf(math.MaxFloat64); b != bucketCount-1 {
+		t.Errorf("bucket of huge latency %d", b)
+	}
+	for _, latency := range []float64{2, 10, 99.5, 1000, 30000} {
+		upper := bucketUpperBound(bucketOf(latency))
+		if upper < latency || upper > latency*bucketFactor {
+			t.Errorf("latency %v in bucket up to %v", latency, upper)
+		}
+	}
+}
+
+func TestHubCompleteIsFinal(t *testing.T) {
+	h := NewHub()
+	events, unsubscribe := h.Subscribe("lt")
+	defer unsubscribe()
+
+	h.Record("lt", "ng", true, 2, entries([]int{10}, 0))
+	h.Status("lt", "complete")
+	h.Complete("lt", map[string]any{"totalRequests": 1})
+
+	types := []string{}
+	for len(types) < 3 {
+		select {
+		case e := <-events:
+			types = append(types, e.Type)
+		case <-time.After(time.Second):
+			t.Fatalf("got events %v", types)
+		}
+	}
+	if types[0] != EventStats || types[1] != EventStatus || types[2] != EventSummary {
+		t.Errorf("events %v", types)
+	}
+	if _, ok := h.Snapshot("lt"); ok {
+		t.Error("tracker kept after completion")
+	}
+}
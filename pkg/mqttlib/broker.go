@@ -0,0 +1,42 @@
+package mqttlib
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+)
+
+/*
+NewBroker - start an mqtt broker inside the current process, for local
+development and small setups which don't run an external broker. Node groups
+connect to host:port, the manager connects over loopback. The broker doesn't
+authenticate clients, anyone who can reach host:port can publish and subscribe
+to every topic, so host should be loopback unless the network is trusted
+
+	b, err := mqttlib.NewBroker("127.0.0.1", 1883)
+	m := mqttlib.NewMqtt("127.0.0.1", 1883)
+	go m.Connect()
+	m.WaitUntilConnected()
+	...
+	b.Close()
+*/
+func NewBroker(host string, port int) (*mochi.Server, error) {
+	broker := mochi.New(nil)
+	if err := broker.AddHook(new(auth.AllowHook), nil); err != nil {
+		return nil, fmt.Errorf("error while adding broker auth hook %s", err.Error())
+	}
+
+	tcp := listeners.NewTCP("tcp", net.JoinHostPort(host, strconv.Itoa(port)), nil)
+	if err := broker.AddListener(tcp); err != nil {
+		return nil, fmt.Errorf("error while starting broker listener %s", err.Error())
+	}
+
+	if err := broker.Serve(); err != nil {
+		return nil, fmt.Errorf("error while starting broker %s", err.Error())
+	}
+	return broker, nil
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"github.com/mridulganga/dlt-manager/pkg/transport"
 )
 
 type MqttClient struct {
@@ -21,8 +23,8 @@ Usage
 	go m.Connect()
 	m.WaitUntilConnected()
 
-	m.Sub("topic", func(client mqtt.Client, message mqtt.Message) {
-	        fmt.Println("Received " + string(message.Payload()))
+	m.Sub("topic", func(msg transport.Message) {
+	        fmt.Println("Received " + string(msg.Payload))
 	})
 
 	m.Publish("topic", "Hello World")
@@ -70,8 +72,14 @@ func (m MqttClient) ConnectLostHandler(client mqtt.Client, err error) {
 	fmt.Printf("Connect lost: %v", err)
 }
 
-func (m MqttClient) Sub(topic string, messageHanler mqtt.MessageHandler) error {
-	token := m.client.Subscribe(topic, 1, messageHanler)
+func (m MqttClient) Sub(topic string, handler transport.Handler) error {
+	token := m.client.Subscribe(topic, 1, func(client mqtt.Client, message mqtt.Message) {
+		handler(transport.Message{
+			Topic:   message.Topic(),
+			Payload: message.Payload(),
+			ReplyTo: transport.ReplyTo(message.Payload()),
+		})
+	})
 	if token.Wait() && token.Error() != nil {
 		fmt.Println("error while sub " + token.Error().Error())
 		return token.Error()
@@ -90,3 +98,44 @@ func (m MqttClient) Publish(topic string, data map[string]any) error {
 	// fmt.Printf("Published to topic: %s\n", topic)
 	return nil
 }
+
+func (m MqttClient) Unsub(topic string) error {
+	token := m.client.Unsubscribe(topic)
+	if token.Wait() && token.Error() != nil {
+		fmt.Println("error while unsub " + token.Error().Error())
+		return token.Error()
+	}
+	return nil
+}
+
+// Request - mqtt has no request/reply, the reply topic is sent in the
+// payload and the first message published to it is returned
+func (m MqttClient) Request(topic string, data map[string]any, timeout time.Duration) (transport.Message, error) {
+	replyTo := fmt.Sprintf("%s/reply/%s", topic, uuid.New().String())
+	replies := make(chan transport.Message, 1)
+	err := m.Sub(replyTo, func(msg transport.Message) {
+		select {
+		case replies <- msg:
+		default:
+		}
+	})
+	if err != nil {
+		return transport.Message{}, err
+	}
+	defer m.Unsub(replyTo)
+
+	if err := m.Publish(topic, transport.WithReplyTo(data, replyTo)); err != nil {
+		return transport.Message{}, err
+	}
+
+	select {
+	case msg := <-replies:
+		return msg, nil
+	case <-time.After(timeout):
+		return transport.Message{}, transport.ErrRequestTimeout
+	}
+}
+
+func (m MqttClient) Close() {
+	m.client.Disconnect(250)
+}
@@ -0,0 +1,107 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Spec - openapi 3 specification of the manager api, keep it in sync with the
+// routes registered in main.go, CheckRoutes reports the differences
+//
+//go:embed openapi.json
+var Spec []byte
+
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>dlt-manager api</title>
+  <meta charset="utf-8"/>
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+</head>
+<body>
+  <redoc spec-url="/api/openapi.json"></redoc>
+  <script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`
+
+// Handler - serves the specification
+func Handler(c *gin.Context) {
+	c.Data(200, "application/json; charset=utf-8", Spec)
+}
+
+// DocsHandler - serves a documentation page rendering the specification
+func DocsHandler(c *gin.Context) {
+	c.Data(200, "text/html; charset=utf-8", []byte(docsPage))
+}
+
+// Operations - "METHOD /path" of every operation in the specification, with
+// path params written the openapi way as {id}
+func Operations() ([]string, error) {
+	spec := struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}{}
+	if err := json.Unmarshal(Spec, &spec); err != nil {
+		return nil, err
+	}
+	ops := []string{}
+	for path, methods := range spec.Paths {
+		for method := range methods {
+			ops = append(ops, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(ops)
+	return ops, nil
+}
+
+// CheckRoutes - error listing the routes missing from the specification and
+// the operations of the specification without a route
+func CheckRoutes(routes gin.RoutesInfo) error {
+	ops, err := Operations()
+	if err != nil {
+		return err
+	}
+	documented := map[string]bool{}
+	for _, op := range ops {
+		documented[op] = true
+	}
+
+	undocumented := []string{}
+	registered := map[string]bool{}
+	for _, route := range routes {
+		op := route.Method + " " + specPath(route.Path)
+		registered[op] = true
+		if !documented[op] {
+			undocumented = append(undocumented, op)
+		}
+	}
+	missing := []string{}
+	for _, op := range ops {
+		if !registered[op] {
+			missing = append(missing, op)
+		}
+	}
+	sort.Strings(undocumented)
+
+	if len(undocumented) == 0 && len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("openapi spec is out of sync with the routes, not in the spec: [%s], without a route: [%s]",
+		strings.Join(undocumented, ", "), strings.Join(missing, ", "))
+}
+
+// specPath - gin path /ngs/:id to the openapi path /ngs/{id}
+func specPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
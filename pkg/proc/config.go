@@ -0,0 +1,45 @@
+package proc
+
+import (
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"github.com/sirupsen/logrus"
+)
+
+// ConfigureMessage - configure action carrying the desired config of a node group
+func ConfigureMessage(ng db.NodeGroup) map[string]any {
+	return map[string]any{
+		"action":         "configure",
+		"config_version": ng.Config.Version,
+		"config":         ng.Config,
+	}
+}
+
+// configPushInterval - a node group which doesn't report the pushed config is
+// sent it again after this long
+const configPushInterval = 30 * time.Second
+
+// pushConfig - send the desired config to a node group which doesn't run it,
+// node groups without a desired config keep their defaults
+func (p *Processor) pushConfig(ng db.NodeGroup) {
+	if ng.Config.Version == 0 || ng.ConfigInSync() {
+		return
+	}
+	if time.Since(p.configPushedAt[ng.ID]) < configPushInterval {
+		return
+	}
+	p.configPushedAt[ng.ID] = time.Now()
+	logrus.Infof("pushing config version %d to node group %s", ng.Config.Version, ng.ID)
+	if err := p.m.Publish(ng.Topic, ConfigureMessage(ng)); err != nil {
+		logrus.Errorf("error while pushing config to %s %v", ng.ID, err.Error())
+	}
+}
+
+// PushConfig - send a changed config right away
+func (p *Processor) PushConfig(ng db.NodeGroup) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.configPushedAt, ng.ID)
+	p.pushConfig(ng)
+}
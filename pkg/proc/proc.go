@@ -1,30 +1,392 @@
 package proc
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"github.com/mridulganga/dlt-manager/pkg/live"
+	"github.com/mridulganga/dlt-manager/pkg/sched"
+	"github.com/mridulganga/dlt-manager/pkg/transport"
+	"github.com/mridulganga/dlt-manager/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // process messages and update db with nodegroup, node and load test data
 
-type Message struct {
-	Action          string   `json:"action"`
-	LoadTestId      string   `json:"load_test_id"`
-	NodeGroupId     string   `json:"ng_id"`
-	NodeGroupStatus string   `json:"ng_status"`
-	Timestamp       string   `json:"timestamp"`
-	Nodes           []string `json:"nodes"`
-	NodeUpdates     []string `json:"node_updates"`
+type Processor struct {
+	d          *db.DB
+	au         *audit.Auditor
+	hub        *live.Hub
+	m          transport.Transport
+	s          *sched.Scheduler
+	namespaces []transport.Namespace
+	mu         sync.Mutex
+
+	// last config push to each node group
+	configPushedAt map[string]time.Time
+}
+
+func NewProcessor(database *db.DB, auditor *audit.Auditor, hub *live.Hub, t transport.Transport, scheduler *sched.Scheduler, namespaces []transport.Namespace) *Processor {
+	return &Processor{
+		d:              database,
+		au:             auditor,
+		hub:            hub,
+		m:              t,
+		s:              scheduler,
+		namespaces:     namespaces,
+		configPushedAt: map[string]time.Time{},
+	}
+}
+
+// Process - handle a single message received on the manager topic of one of the
+// namespaces, returns an error when the message could not be processed so it
+// can be dead lettered
+func (p *Processor) Process(msg transport.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ns, ok := transport.NamespaceOf(msg.Topic, p.namespaces)
+	if !ok {
+		return fmt.Errorf("topic %s is outside the manager namespaces", msg.Topic)
+	}
+
+	data := db.NGHeartbeat{}
+	if err := json.Unmarshal(msg.Payload, &data); err != nil {
+		return fmt.Errorf("error while decoding heartbeat %s", err.Error())
+	}
+	// transports with native replies don't put the reply topic in the payload
+	if msg.ReplyTo != "" {
+		data.ReplyTo = msg.ReplyTo
+	}
+
+	switch data.Action {
+	case "ng_update":
+		return p.processNodeGroupUpdate(ns, data)
+	case "register":
+		return p.processRegister(ns, data)
+	default:
+		return fmt.Errorf("invalid action %s", data.Action)
+	}
+}
+
+func (p *Processor) processNodeGroupUpdate(ns transport.Namespace, data db.NGHeartbeat) error {
+	logrus.Info("processing ng_update")
+	isNGHealthy := data.NodeGroupStatus == "healthy"
+	healthStatus := db.HealthUnhealthy
+	if isNGHealthy {
+		healthStatus = db.HealthHealthy
+	}
+
+	// node groups only report to the manager topic of their own namespace
+	ng, err := p.d.GetNodeGroupByID(data.NodeGroupID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("node group %s is not registered", data.NodeGroupID)
+	}
+	if err != nil {
+		return fmt.Errorf("error while GetNodeGroupByID %s", err.Error())
+	}
+	if ng.Namespace != string(ns) {
+		return fmt.Errorf("node group %s is not in namespace %s", data.NodeGroupID, ns)
+	}
+
+	// update ng health db collection
+	err = p.d.UpdateNodeGroupHealth(data.NodeGroupID, isNGHealthy)
+	if err != nil {
+		logrus.Errorf("error while UpdateNodeGroupHealth %v", err.Error())
+	} else {
+		if ng.Health() != healthStatus {
+			p.au.System(audit.Health, audit.NodeGroup, ng.ID,
+				map[string]any{"is_healthy": ng.IsHealthy, "health_status": ng.Health()},
+				map[string]any{"is_healthy": isNGHealthy, "health_status": healthStatus})
+		}
+		// the first heartbeat starts the health history
+		if ng.Health() != healthStatus || ng.LastHealthCheck.IsZero() {
+			p.healthEvent(ng, healthStatus)
+		}
+	}
+
+	// update node list if ng healthy
+	if isNGHealthy {
+		p.d.UpdateNodeGroup(data.NodeGroupID, bson.M{"nodes": data.Nodes})
+	}
+	if data.ConfigVersion != ng.AppliedConfigVersion {
+		if _, err := p.d.UpdateNodeGroup(ng.ID, bson.M{"applied_config_version": data.ConfigVersion}); err != nil {
+			logrus.Errorf("error while updating applied config of %s %v", ng.ID, err.Error())
+		}
+		ng.AppliedConfigVersion = data.ConfigVersion
+	}
+	// node groups which restarted or missed the push run an older config
+	if isNGHealthy {
+		p.pushConfig(*ng)
+	}
+
+	running := data.RunningLoadTests()
+
+	// a drained node group is done once it stopped its load tests
+	if ng.Draining && len(running) == 0 {
+		if _, err := p.d.UpdateNodeGroup(ng.ID, bson.M{"draining": false}); err != nil {
+			logrus.Errorf("error while updating draining of %s %v", ng.ID, err.Error())
+		}
+	}
+	if data.MultiTenant != ng.MultiTenant {
+		if _, err := p.d.UpdateNodeGroup(ng.ID, bson.M{"multi_tenant": data.MultiTenant}); err != nil {
+			logrus.Errorf("error while updating multi tenancy of %s %v", ng.ID, err.Error())
+		}
+	}
+	if data.Capacity != nil {
+		capacity := *data.Capacity
+		capacity.ReportedAt = time.Now()
+		if _, err := p.d.UpdateNodeGroup(data.NodeGroupID, bson.M{"capacity": capacity}); err != nil {
+			logrus.Errorf("error while updating capacity of %s %v", data.NodeGroupID, err.Error())
+		}
+	}
+
+	// node updates are optional, running node groups may send heartbeats
+	// without results
+	nodeUpdates := db.NodeUpdates{}
+	if data.NodeUpdates != "" {
+		if err := json.Unmarshal([]byte(data.NodeUpdates), &nodeUpdates); err != nil {
+			return fmt.Errorf("error while decoding node updates %s", err.Error())
+		}
+	}
+	p.updateNodes(ng.ID, data.Nodes, isNGHealthy, nodeUpdates)
+
+	// results go to the load test their node reports, or the one of the
+	// heartbeat, as long as the node group runs it
+	byLoadTest := splitByLoadTest(nodeUpdates, data.LoadTestId)
+	for _, loadTestId := range running {
+		lt, err := p.checkBinding(ng, loadTestId)
+		if err != nil {
+			logrus.Warnf("ignoring results of node group %s %v", ng.ID, err.Error())
+			continue
+		}
+		if lt.AcknowledgedAt.IsZero() {
+			if _, err := p.d.UpdateLoadTest(lt.ID, bson.M{"acknowledged_at": time.Now()}); err != nil {
+				logrus.Errorf("error while acknowledging load test %s %v", lt.ID, err.Error())
+			}
+		}
+		if !slices.Contains(lt.StartedNodeGroups, ng.ID) || slices.Contains(lt.DoneNodeGroups, ng.ID) {
+			if _, err := p.d.MarkLoadTestNodeGroupRunning(lt.ID, ng.ID); err != nil {
+				logrus.Errorf("error while MarkLoadTestNodeGroupRunning %v", err.Error())
+			}
+		}
+		entries, err := p.d.PushLoadTestResult(loadTestId, byLoadTest[loadTestId])
+		if err != nil {
+			return fmt.Errorf("error while PushLoadTestResult %s", err.Error())
+		}
+		p.hub.Record(loadTestId, ng.ID, isNGHealthy, len(data.Nodes), entries)
+	}
+
+	return p.completeLoadTests(ns, ng, running)
 }
 
-func Proc(msg Message) error {
-	if msg.Action != "ng_update" {
-		return fmt.Errorf("invalid action %s", msg.Action)
+// completeLoadTests - the node group is done with the load tests it takes part
+// in but no longer runs, once it ran them or missed their start. A load test is
+// complete once all of its node groups are done, which is kept with the load
+// test so that restarts of the manager don't lose it
+func (p *Processor) completeLoadTests(ns transport.Namespace, ng *db.NodeGroup, running []string) error {
+	loadtests, err := p.d.ListRunningLoadTestForNodeGroup(*ng)
+	if err != nil {
+		return fmt.Errorf("error while ListRunningLoadTestForNodeGroup %s", err.Error())
+	}
+	for _, lt := range *loadtests {
+		if slices.Contains(running, lt.ID) {
+			continue
+		}
+		if !slices.Contains(lt.StartedNodeGroups, ng.ID) && !p.s.StartMissed(lt, *ng) {
+			continue
+		}
+		if !slices.Contains(lt.DoneNodeGroups, ng.ID) {
+			updated, err := p.d.MarkLoadTestNodeGroupDone(lt.ID, ng.ID)
+			if err != nil {
+				logrus.Errorf("error while MarkLoadTestNodeGroupDone %v", err.Error())
+				continue
+			}
+			lt = *updated
+		}
+		if !lt.IsDone() {
+			continue
+		}
+		if err := p.complete(lt); err != nil {
+			return err
+		}
+		// the node groups are idle again
+		p.s.Dispatch(string(ns))
 	}
+	return nil
+}
+
+// checkBinding - node groups only report results of running load tests they
+// are bound to, returns the load test
+func (p *Processor) checkBinding(ng *db.NodeGroup, loadTestId string) (*db.LoadTest, error) {
+	lt, err := p.d.GetLoadTestByID(loadTestId)
+	if err != nil {
+		return nil, fmt.Errorf("load test %s %s", loadTestId, err.Error())
+	}
+	if lt.Namespace != ng.Namespace || !lt.RunsOn(ng.ID) {
+		return nil, fmt.Errorf("load test %s doesn't run on it", loadTestId)
+	}
+	if !lt.IsRunning() {
+		return nil, fmt.Errorf("load test %s is %s", loadTestId, lt.Status)
+	}
+	return lt, nil
+}
 
-	// update node group health status
-	// update node group node list and their health
-	// update isLoadTest active
-	// update load test results
+// complete - only a running load test completes, a load test which failed in
+// the meantime already got its summary. Stopping load tests are stopped
+func (p *Processor) complete(lt db.LoadTest) error {
+	updated, err := p.d.UpdateLoadTestIfStatus(lt.ID, lt.Status, bson.M{"status": lt.EndStatus("complete"), "end_time": time.Now()})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error while completing load test %s %s", lt.ID, err.Error())
+	}
+	p.au.System(audit.Complete, audit.LoadTest, lt.ID, lt, updated)
+	p.hub.Status(lt.ID, updated.Status)
 
+	// 	consolidate results and push to result collection
+	ltSummary, err := p.d.FetchLoadTestResults(lt.ID)
+	if err != nil {
+		return fmt.Errorf("error while FetchLoadTestResults %s", err.Error())
+	}
+	if _, err := p.d.CreateLoadTestSummary(ltSummary); err != nil {
+		return fmt.Errorf("error while CreateLoadTestSummary %s", err.Error())
+	}
+	p.hub.Complete(lt.ID, ltSummary)
 	return nil
 }
+
+// splitByLoadTest - node updates by the load test their node reports, updates
+// without one belong to loadTestId
+func splitByLoadTest(nodeUpdates db.NodeUpdates, loadTestId string) map[string]db.NodeUpdates {
+	result := map[string]db.NodeUpdates{}
+	for nodeId, updates := range nodeUpdates {
+		for _, update := range updates {
+			heartbeat := db.NodeHeartBeat{}
+			utils.DeepCopy(update, &heartbeat)
+			id := heartbeat.LoadTestID
+			if id == "" {
+				id = loadTestId
+			}
+			if result[id] == nil {
+				result[id] = db.NodeUpdates{}
+			}
+			result[id][nodeId] = append(result[id][nodeId], update)
+		}
+	}
+	return result
+}
+
+// updateNodes - keep the node inventory of a node group in line with its
+// heartbeat, only healthy node groups list all of their nodes so nodes only
+// leave when a healthy heartbeat doesn't list them
+func (p *Processor) updateNodes(ngId string, listed []string, isListComplete bool, nodeUpdates db.NodeUpdates) {
+	known, err := p.d.ListNodeByNodeGroup(ngId)
+	if err != nil {
+		logrus.Errorf("error while ListNodeByNodeGroup %v", err.Error())
+		return
+	}
+	current := map[string]db.Node{}
+	for _, node := range *known {
+		current[node.NodeID] = node
+	}
+
+	// nodes sending updates are part of the node group even when not listed
+	nodeIds := append([]string{}, listed...)
+	for nodeId := range nodeUpdates {
+		if !slices.Contains(nodeIds, nodeId) {
+			nodeIds = append(nodeIds, nodeId)
+		}
+	}
+
+	now := time.Now()
+	seen := map[string]bool{}
+	for _, nodeId := range nodeIds {
+		seen[nodeId] = true
+		node, ok := current[nodeId]
+		joined := !ok || node.HasLeft()
+		if joined {
+			node = db.Node{NodeID: nodeId, NodeGroupID: ngId, Status: "unknown", JoinedAt: now}
+		}
+		previousStatus := node.Status
+
+		if updates := nodeUpdates[nodeId]; len(updates) > 0 {
+			heartbeat := db.NodeHeartBeat{}
+			utils.DeepCopy(updates[len(updates)-1], &heartbeat)
+			if heartbeat.NodeStatus != "" {
+				node.Status = heartbeat.NodeStatus
+			}
+			if heartbeat.Version != "" {
+				node.Version = heartbeat.Version
+			}
+			if heartbeat.Capacity > 0 {
+				node.Capacity = heartbeat.Capacity
+			}
+			node.LoadTestID = ""
+			if heartbeat.IsTestActive == "true" {
+				node.LoadTestID = heartbeat.LoadTestID
+			}
+		}
+		node.LastSeen = now
+
+		if _, err := p.d.UpsertNode(&node); err != nil {
+			logrus.Errorf("error while UpsertNode %v", err.Error())
+			continue
+		}
+		switch {
+		case joined:
+			p.nodeEvent(node, db.NodeEventJoined)
+		case previousStatus != node.Status:
+			p.nodeEvent(node, db.NodeEventStatus)
+		}
+	}
+
+	if !isListComplete {
+		return
+	}
+	for nodeId, node := range current {
+		if seen[nodeId] || node.HasLeft() {
+			continue
+		}
+		left, err := p.d.MarkNodeLeft(ngId, nodeId)
+		if err != nil {
+			logrus.Errorf("error while MarkNodeLeft %v", err.Error())
+			continue
+		}
+		p.nodeEvent(*left, db.NodeEventLeft)
+	}
+}
+
+// healthEvent - record a health transition, from is empty for the first
+// heartbeat of a node group
+func (p *Processor) healthEvent(ng *db.NodeGroup, healthStatus string) {
+	from := ng.Health()
+	if ng.LastHealthCheck.IsZero() {
+		from = ""
+	}
+	_, err := p.d.CreateHealthEvent(&db.HealthEvent{NodeGroupID: ng.ID, From: from, To: healthStatus})
+	if err != nil {
+		logrus.Errorf("error while CreateHealthEvent %v", err.Error())
+	}
+}
+
+func (p *Processor) nodeEvent(node db.Node, event string) {
+	_, err := p.d.CreateNodeEvent(&db.NodeEvent{
+		NodeID:      node.NodeID,
+		NodeGroupID: node.NodeGroupID,
+		Event:       event,
+		Status:      node.Status,
+	})
+	if err != nil {
+		logrus.Errorf("error while CreateNodeEvent %v", err.Error())
+	}
+}
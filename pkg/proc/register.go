@@ -0,0 +1,175 @@
+package proc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/auth"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"github.com/mridulganga/dlt-manager/pkg/transport"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// registration statuses replied to node groups
+const (
+	registrationApproved = "approved"
+	registrationPending  = "pending"
+	registrationRejected = "rejected"
+)
+
+// processRegister - create a node group announcing itself with an enrollment
+// token, it is given a topic in the namespace and is approved right away or
+// waits for an admin depending on the token. Registering again is answered with
+// the current registration so node groups can simply register on every start.
+// Rejections are only answered, never dead lettered, as the payload carries the
+// enrollment token
+func (p *Processor) processRegister(ns transport.Namespace, data db.NGHeartbeat) error {
+	logrus.Infof("processing register of %s", data.NodeGroupID)
+
+	ng, err := p.register(ns, data)
+	if err != nil {
+		logrus.Warnf("registration of node group %s rejected %v", data.NodeGroupID, err.Error())
+		p.replyRegistration(ns, data, map[string]any{
+			"status": registrationRejected,
+			"error":  err.Error(),
+		})
+		return nil
+	}
+
+	status := registrationApproved
+	if ng.Pending {
+		status = registrationPending
+	}
+	p.replyRegistration(ns, data, map[string]any{
+		"status": status,
+		"ng_id":  ng.ID,
+		"topic":  ng.Topic,
+	})
+	// a node group registering again may have restarted on its defaults
+	if data.ConfigVersion != ng.AppliedConfigVersion {
+		ng.AppliedConfigVersion = data.ConfigVersion
+		p.pushConfig(*ng)
+	}
+	return nil
+}
+
+func (p *Processor) register(ns transport.Namespace, data db.NGHeartbeat) (*db.NodeGroup, error) {
+	if data.NodeGroupID == "" || strings.ContainsAny(data.NodeGroupID, "/+#*> ") {
+		return nil, fmt.Errorf("invalid node group id %q", data.NodeGroupID)
+	}
+	token, err := p.d.GetEnrollmentTokenByHash(auth.HashAPIKey(data.EnrollmentToken))
+	if err != nil || !token.IsActive() {
+		return nil, errors.New("invalid enrollment token")
+	}
+	if token.Namespace != "" && token.Namespace != string(ns) {
+		return nil, fmt.Errorf("enrollment token is not valid in namespace %s", ns)
+	}
+
+	existing, err := p.d.GetNodeGroupByID(data.NodeGroupID)
+	if err == nil {
+		if existing.Namespace != string(ns) || existing.ProjectID != token.ProjectID {
+			return nil, fmt.Errorf("node group %s is already registered elsewhere", data.NodeGroupID)
+		}
+		return existing, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	topic := ns.Topic("ng/" + data.NodeGroupID)
+	if err := ns.ValidateTopic(topic); err != nil {
+		return nil, err
+	}
+	// registering counts as the first heartbeat, so the node group goes stale
+	// like any other when no heartbeats follow
+	ng, err := p.d.CreateNodeGroup(&db.NodeGroup{
+		ID:              data.NodeGroupID,
+		Topic:           topic,
+		Namespace:       string(ns),
+		ProjectID:       token.ProjectID,
+		Shared:          token.Shared,
+		Nodes:           data.Nodes,
+		IsHealthy:       true,
+		HealthStatus:    db.HealthHealthy,
+		LastHealthCheck: time.Now(),
+		Pending:         !token.AutoApprove,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.d.CreateHealthEvent(&db.HealthEvent{NodeGroupID: ng.ID, To: db.HealthHealthy}); err != nil {
+		logrus.Errorf("error while CreateHealthEvent %v", err.Error())
+	}
+	if err := p.d.UseEnrollmentToken(token.ID); err != nil {
+		logrus.Errorf("error while UseEnrollmentToken %v", err.Error())
+	}
+	p.au.Record(db.AuditEntry{
+		Actor:      token.ID,
+		ActorType:  db.ActorTypeSystem,
+		Action:     audit.Register,
+		TargetType: audit.NodeGroup,
+		TargetID:   ng.ID,
+	}, nil, ng)
+	return ng, nil
+}
+
+// replyRegistration - answer on the reply topic of the request, node groups
+// which didn't give one get the answer on registration/<ng_id> in the namespace.
+// Reply topics outside the namespace of the request are never published to
+func (p *Processor) replyRegistration(ns transport.Namespace, data db.NGHeartbeat, reply map[string]any) {
+	topic := data.ReplyTo
+	if topic != "" && !p.isReplyTopic(ns, topic) {
+		logrus.Warnf("not replying to registration of %s on %s outside namespace %s", data.NodeGroupID, topic, ns)
+		return
+	}
+	if topic == "" {
+		if data.NodeGroupID == "" || strings.ContainsAny(data.NodeGroupID, "/+#*> ") {
+			return
+		}
+		topic = ns.Topic("registration/" + data.NodeGroupID)
+	}
+	reply["action"] = "registration"
+	if err := p.m.Publish(topic, reply); err != nil {
+		logrus.Errorf("error while replying to registration of %s %v", data.NodeGroupID, err.Error())
+	}
+}
+
+// isReplyTopic - topic lies in ns and in no more specific namespace of the
+// manager, and isn't one of the manager topics
+func (p *Processor) isReplyTopic(ns transport.Namespace, topic string) bool {
+	if err := ns.ValidateTopic(topic); err != nil {
+		return false
+	}
+	if owner, _ := transport.NamespaceOf(topic, p.namespaces); owner != ns {
+		return false
+	}
+	for _, n := range p.namespaces {
+		if topic == n.Topic("manager") {
+			return false
+		}
+	}
+	return true
+}
+
+// RedactPayload - payload with the enrollment token removed so that it can be
+// stored, payloads which aren't json objects are returned as they are
+func RedactPayload(payload []byte) []byte {
+	data := map[string]any{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return payload
+	}
+	if _, ok := data["enrollment_token"]; !ok {
+		return payload
+	}
+	data["enrollment_token"] = "REDACTED"
+	redacted, err := json.Marshal(data)
+	if err != nil {
+		return payload
+	}
+	return redacted
+}
@@ -0,0 +1,340 @@
+package sched
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"github.com/mridulganga/dlt-manager/pkg/live"
+	"github.com/mridulganga/dlt-manager/pkg/transport"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// start queued load tests on idle node groups, every load test is bound to
+// the node groups it started on and a node group serves one load test at a
+// time unless it declared to be multi tenant
+
+// startAckHeartbeats - heartbeat intervals a node group gets to report a load
+// test it was told to start
+const startAckHeartbeats = 3
+
+type Scheduler struct {
+	d          *db.DB
+	au         *audit.Auditor
+	hub        *live.Hub
+	m          transport.Transport
+	namespaces []transport.Namespace
+	interval   time.Duration
+	startedAt  time.Time
+	mu         sync.Mutex
+}
+
+func NewScheduler(database *db.DB, auditor *audit.Auditor, hub *live.Hub, t transport.Transport, namespaces []transport.Namespace, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		d:          database,
+		au:         auditor,
+		hub:        hub,
+		m:          t,
+		namespaces: namespaces,
+		interval:   interval,
+		startedAt:  time.Now(),
+	}
+}
+
+// Run - dispatch every interval until ctx is done, load tests which end
+// without a heartbeat, ie. failed by the health monitor, are noticed here
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, ns := range s.namespaces {
+				s.Dispatch(string(ns))
+			}
+		}
+	}
+}
+
+// Dispatch - start the queued load tests of the namespace which find idle node
+// groups, in queue order. Node groups a queued load test waits for are kept
+// for it so the load tests behind it can't starve it, load tests bound to node
+// groups which no longer exist are cancelled
+func (s *Scheduler) Dispatch(namespace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	running, err := s.d.ListRunningLoadTestByNamespace(namespace)
+	if err != nil {
+		logrus.Errorf("error while ListRunningLoadTestByNamespace %v", err.Error())
+		return
+	}
+	busy := map[string]bool{}
+	for _, lt := range *running {
+		if s.missedStart(lt) {
+			s.fail(lt)
+			continue
+		}
+		ngIds := lt.NodeGroups
+		if len(ngIds) == 0 {
+			// started before node groups were bound, it may run on any node
+			// group of its project
+			nodegroups, err := s.d.ListNodeGroupForProjectByNamespace(lt.ProjectID, lt.Namespace)
+			if err != nil {
+				logrus.Errorf("error while ListNodeGroupForProjectByNamespace %v", err.Error())
+				return
+			}
+			for _, ng := range *nodegroups {
+				ngIds = append(ngIds, ng.ID)
+			}
+		}
+		for _, ngId := range ngIds {
+			// node groups done with it can take the next one
+			if !slices.Contains(lt.DoneNodeGroups, ngId) {
+				busy[ngId] = true
+			}
+		}
+	}
+
+	queued, err := s.d.ListQueuedLoadTest("", namespace)
+	if err != nil {
+		logrus.Errorf("error while ListQueuedLoadTest %v", err.Error())
+		return
+	}
+	reserved := map[string]bool{}
+	for _, lt := range *queued {
+		nodegroups, err := s.d.ListNodeGroupForProjectByNamespace(lt.ProjectID, lt.Namespace)
+		if err != nil {
+			logrus.Errorf("error while ListNodeGroupForProjectByNamespace %v", err.Error())
+			return
+		}
+		candidates := Targets(*nodegroups)
+		if len(lt.NodeGroups) > 0 {
+			if existing := bound(*nodegroups, lt.NodeGroups); len(existing) != len(lt.NodeGroups) {
+				s.cancel(lt, fmt.Sprintf("%d of its %d node groups were deleted", len(lt.NodeGroups)-len(existing), len(lt.NodeGroups)))
+				continue
+			}
+			candidates = bound(candidates, lt.NodeGroups)
+		}
+
+		idle := []db.NodeGroup{}
+		for _, ng := range candidates {
+			if !reserved[ng.ID] && (!busy[ng.ID] || ng.MultiTenant) {
+				idle = append(idle, ng)
+			}
+		}
+		if err := s.canStart(lt, idle); err != nil {
+			logrus.Infof("load test %s stays queued, %s", lt.ID, err.Error())
+			// only wait for busy node groups, a load test which couldn't start
+			// even with all of them idle, eg. as one it is bound to is cordoned
+			// or unhealthy, would hold them for nothing
+			if s.canStart(lt, candidates) == nil {
+				for _, ng := range candidates {
+					reserved[ng.ID] = true
+				}
+			}
+			continue
+		}
+		if s.start(lt, idle) {
+			for _, ng := range idle {
+				busy[ng.ID] = true
+			}
+		}
+	}
+}
+
+// canStart - load tests bound to node groups wait for all of them, the others
+// start on the idle node groups when they have the capacity
+func (s *Scheduler) canStart(lt db.LoadTest, idle []db.NodeGroup) error {
+	if len(lt.NodeGroups) > 0 && len(idle) != len(lt.NodeGroups) {
+		return fmt.Errorf("%d of its %d node groups are idle and healthy", len(idle), len(lt.NodeGroups))
+	}
+	return CheckCapacity(idle, &lt)
+}
+
+func (s *Scheduler) start(lt db.LoadTest, targets []db.NodeGroup) bool {
+	ngIds := []string{}
+	for _, ng := range targets {
+		ngIds = append(ngIds, ng.ID)
+	}
+	updated, err := s.d.UpdateLoadTestIfStatus(lt.ID, "queued", bson.M{"status": "running", "start_time": time.Now(), "node_groups": ngIds})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// cancelled in the meantime
+		return false
+	}
+	if err != nil {
+		logrus.Errorf("error while starting load test %s %v", lt.ID, err.Error())
+		return false
+	}
+	logrus.Infof("starting load test %s on %d node groups", lt.ID, len(targets))
+
+	// trigger load test in all node groups
+	for _, ng := range targets {
+		s.m.Publish(ng.Topic, map[string]any{
+			"action":       "start_loadtest",
+			"load_test_id": updated.ID,
+			"plugin_data":  updated.Logic,
+			"duration":     updated.Duration,
+			"tps":          updated.TPS,
+		})
+	}
+	s.au.System(audit.Start, audit.LoadTest, lt.ID, lt, updated)
+	s.hub.Status(lt.ID, updated.Status)
+	return true
+}
+
+// missedStart - no node group picked up the load test in time
+func (s *Scheduler) missedStart(lt db.LoadTest) bool {
+	if !lt.AcknowledgedAt.IsZero() {
+		return false
+	}
+	if time.Since(s.startDeadlineFrom(lt)) <= s.interval*startAckHeartbeats {
+		return false
+	}
+	nodegroups, err := s.d.ListNodeGroupForProjectByNamespace(lt.ProjectID, lt.Namespace)
+	if err != nil {
+		logrus.Errorf("error while ListNodeGroupForProjectByNamespace %v", err.Error())
+		return false
+	}
+	for _, ng := range *nodegroups {
+		if lt.RunsOn(ng.ID) && !s.StartMissed(lt, ng) {
+			return false
+		}
+	}
+	return true
+}
+
+// StartMissed - the node group had its chance to pick up the load test, since
+// it started or since the scheduler started as acknowledgements before a
+// restart may not have been seen. Node groups configured with a longer
+// heartbeat interval get longer
+func (s *Scheduler) StartMissed(lt db.LoadTest, ng db.NodeGroup) bool {
+	interval := s.interval
+	if configured := time.Duration(ng.Config.HeartbeatInterval) * time.Second; configured > interval {
+		interval = configured
+	}
+	return time.Since(s.startDeadlineFrom(lt)) > interval*startAckHeartbeats
+}
+
+func (s *Scheduler) startDeadlineFrom(lt db.LoadTest) time.Time {
+	if s.startedAt.After(lt.StartTime) {
+		return s.startedAt
+	}
+	return lt.StartTime
+}
+
+// fail - a running load test which none of its node groups picked up, they are
+// told to stop it in case the start only got delayed
+func (s *Scheduler) fail(lt db.LoadTest) {
+	updated, err := s.d.UpdateLoadTestIfStatus(lt.ID, lt.Status, bson.M{"status": lt.EndStatus("failed"), "end_time": time.Now()})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return
+	}
+	if err != nil {
+		logrus.Errorf("error while failing load test %s %v", lt.ID, err.Error())
+		return
+	}
+	logrus.Warnf("load test %s %s, no node group started it", lt.ID, updated.Status)
+	s.au.System(audit.Fail, audit.LoadTest, lt.ID, lt, updated)
+	s.hub.Status(lt.ID, updated.Status)
+
+	nodegroups, err := s.d.ListNodeGroupForProjectByNamespace(lt.ProjectID, lt.Namespace)
+	if err != nil {
+		logrus.Errorf("error while ListNodeGroupForProjectByNamespace %v", err.Error())
+	} else {
+		for _, ng := range *nodegroups {
+			if lt.RunsOn(ng.ID) {
+				s.m.Publish(ng.Topic, map[string]any{
+					"action":       "stop_loadtest",
+					"load_test_id": lt.ID,
+				})
+			}
+		}
+	}
+	if summary, err := s.d.FetchLoadTestResults(lt.ID); err == nil {
+		s.hub.Complete(lt.ID, summary)
+	}
+}
+
+// cancel - a queued load test which can never start, followers get the empty
+// summary of a load test which never ran
+func (s *Scheduler) cancel(lt db.LoadTest, reason string) {
+	updated, err := s.d.UpdateLoadTestIfStatus(lt.ID, "queued", bson.M{"status": "cancelled", "end_time": time.Now()})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return
+	}
+	if err != nil {
+		logrus.Errorf("error while cancelling load test %s %v", lt.ID, err.Error())
+		return
+	}
+	logrus.Warnf("load test %s cancelled, %s", lt.ID, reason)
+	s.au.System(audit.Cancel, audit.LoadTest, lt.ID, lt, updated)
+	s.hub.Status(lt.ID, updated.Status)
+	if summary, err := s.d.FetchLoadTestResults(lt.ID); err == nil {
+		s.hub.Complete(lt.ID, summary)
+	}
+}
+
+// bound - the node groups of ngIds
+func bound(nodegroups []db.NodeGroup, ngIds []string) []db.NodeGroup {
+	result := []db.NodeGroup{}
+	for _, ng := range nodegroups {
+		if slices.Contains(ngIds, ng.ID) {
+			result = append(result, ng)
+		}
+	}
+	return result
+}
+
+// Targets - node groups a load test starts on, the schedulable ones which
+// are healthy
+func Targets(nodegroups []db.NodeGroup) []db.NodeGroup {
+	targets := []db.NodeGroup{}
+	for _, ng := range Schedulable(nodegroups) {
+		if ng.Health() == db.HealthHealthy {
+			targets = append(targets, ng)
+		}
+	}
+	return targets
+}
+
+// Schedulable - node groups which take new load tests, healthy or not
+func Schedulable(nodegroups []db.NodeGroup) []db.NodeGroup {
+	schedulable := []db.NodeGroup{}
+	for _, ng := range nodegroups {
+		if !ng.Cordoned {
+			schedulable = append(schedulable, ng)
+		}
+	}
+	return schedulable
+}
+
+// CheckCapacity - refuse load tests the node groups can't generate together,
+// node groups which never reported their capacity can't be judged so any of
+// them lets the load test through
+func CheckCapacity(nodegroups []db.NodeGroup, lt *db.LoadTest) error {
+	if len(nodegroups) == 0 {
+		return apierr.New(409, apierr.CodeNoCapacity, "no node group can run load tests in namespace %s", lt.Namespace)
+	}
+	capacity := 0.0
+	for _, ng := range nodegroups {
+		if !ng.Capacity.IsKnown() {
+			return nil
+		}
+		capacity += ng.Capacity.MaxTPS
+	}
+	if lt.TPS > capacity {
+		return apierr.New(409, apierr.CodeNoCapacity, "tps %v exceeds the capacity %v of %d node groups", lt.TPS, capacity, len(nodegroups))
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package sched
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+)
+
+func nodeGroup(maxTPS float64) db.NodeGroup {
+	return db.NodeGroup{Capacity: db.NodeGroupCapacity{MaxTPS: maxTPS}}
+}
+
+func TestCheckCapacity(t *testing.T) {
+	tests := []struct {
+		name       string
+		nodegroups []db.NodeGroup
+		tps        float64
+		ok         bool
+	}{
+		{"no node groups", nil, 10, false},
+		{"single group within capacity", []db.NodeGroup{nodeGroup(100)}, 100, true},
+		{"single group over capacity", []db.NodeGroup{nodeGroup(100)}, 101, false},
+		{"groups add up", []db.NodeGroup{nodeGroup(100), nodeGroup(50)}, 150, true},
+		{"over the sum", []db.NodeGroup{nodeGroup(100), nodeGroup(50)}, 151, false},
+		{"unknown capacity lets it through", []db.NodeGroup{nodeGroup(100), nodeGroup(0)}, 1000, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCapacity(tt.nodegroups, &db.LoadTest{TPS: tt.tps})
+			if (err == nil) != tt.ok {
+				t.Fatalf("CheckCapacity() = %v, want ok %v", err, tt.ok)
+			}
+			var apiErr *apierr.Error
+			if err != nil && (!errors.As(err, &apiErr) || apiErr.Status != 409 || apiErr.Code != apierr.CodeNoCapacity) {
+				t.Errorf("CheckCapacity() = %#v, want a 409 %s", err, apierr.CodeNoCapacity)
+			}
+		})
+	}
+}
+
+func TestStartMissed(t *testing.T) {
+	now := time.Now()
+	s := &Scheduler{interval: 10 * time.Second, startedAt: now.Add(-time.Hour)}
+	ng := db.NodeGroup{}
+	slow := db.NodeGroup{Config: db.NodeGroupConfig{HeartbeatInterval: 60}}
+
+	if s.StartMissed(db.LoadTest{StartTime: now.Add(-20 * time.Second)}, ng) {
+		t.Error("missed within three heartbeats")
+	}
+	if !s.StartMissed(db.LoadTest{StartTime: now.Add(-40 * time.Second)}, ng) {
+		t.Error("not missed after three heartbeats")
+	}
+	if s.StartMissed(db.LoadTest{StartTime: now.Add(-40 * time.Second)}, slow) {
+		t.Error("missed within three heartbeats of the node group")
+	}
+
+	// load tests started before the scheduler get the whole window again
+	restarted := &Scheduler{interval: 10 * time.Second, startedAt: now.Add(-20 * time.Second)}
+	if restarted.StartMissed(db.LoadTest{StartTime: now.Add(-time.Hour)}, ng) {
+		t.Error("missed right after a restart")
+	}
+}
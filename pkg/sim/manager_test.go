@@ -0,0 +1,129 @@
+package sim_test
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"github.com/mridulganga/dlt-manager/pkg/live"
+	"github.com/mridulganga/dlt-manager/pkg/proc"
+	"github.com/mridulganga/dlt-manager/pkg/sched"
+	"github.com/mridulganga/dlt-manager/pkg/sim"
+	"github.com/mridulganga/dlt-manager/pkg/transport"
+)
+
+// TestLoadTestOnSimulatedNodeGroup - a load test runs from the queue to its
+// summary on a simulated node group, the processor needs a mongo given with
+// DLT_TEST_MONGO
+func TestLoadTestOnSimulatedNodeGroup(t *testing.T) {
+	uri := os.Getenv("DLT_TEST_MONGO")
+	if uri == "" {
+		t.Skip("DLT_TEST_MONGO not set")
+	}
+	d, err := db.NewDatabase(uri, "dlt_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a namespace of its own keeps runs apart in the shared database
+	ns := transport.Namespace("simtest-" + uuid.New().String())
+	namespaces := []transport.Namespace{ns}
+	tr := transport.NewInProc()
+	defer tr.Close()
+
+	au := audit.NewAuditor(d)
+	hub := live.NewHub()
+	s := sched.NewScheduler(d, au, hub, tr, namespaces, 100*time.Millisecond)
+	p := proc.NewProcessor(d, au, hub, tr, s, namespaces)
+
+	var mu sync.Mutex
+	errs := []error{}
+	tr.Sub(ns.Topic("manager"), func(msg transport.Message) {
+		if err := p.Process(msg); err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		}
+	})
+
+	ng, err := d.CreateNodeGroup(&db.NodeGroup{
+		Topic:     ns.Topic("ng/sim"),
+		Namespace: string(ns),
+		ProjectID: string(ns),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.DeleteNodeGroup(ng.ID)
+
+	g := sim.NewNodeGroup(tr, sim.Config{
+		NodeGroupID:       ng.ID,
+		Namespace:         ns,
+		Topic:             ng.Topic,
+		Nodes:             2,
+		HeartbeatInterval: 100 * time.Millisecond,
+		Latency:           sim.Latency{MeanMs: 50, StdDevMs: 20},
+		ErrorRate:         0.1,
+		Seed:              1,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go g.Run(ctx)
+
+	waitFor(t, "node group to become healthy", func() bool {
+		current, err := d.GetNodeGroupByID(ng.ID)
+		return err == nil && current.Health() == db.HealthHealthy
+	})
+
+	lt, err := d.CreateLoadTest(&db.LoadTest{
+		TPS:       50,
+		Duration:  1,
+		Logic:     "sim",
+		Status:    "queued",
+		Namespace: string(ns),
+		ProjectID: string(ns),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.DeleteLoadTest(lt.ID)
+	s.Dispatch(string(ns))
+
+	waitFor(t, "load test to complete", func() bool {
+		current, err := d.GetLoadTestByID(lt.ID)
+		return err == nil && current.Status == "complete"
+	})
+
+	summary, err := d.FetchLoadTestResults(lt.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total := summary["totalRequests"].(int); total < 49 || total > 50 {
+		t.Fatalf("got %d results, want 50", total)
+	}
+	if failures := summary["failureCount"].(int); failures == 0 {
+		t.Fatal("no failures with an error rate of 0.1")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, err := range errs {
+		t.Errorf("processing failed %v", err)
+	}
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
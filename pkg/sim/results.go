@@ -0,0 +1,66 @@
+package sim
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"strconv"
+)
+
+// failures returned by failing requests, picked at random
+var failures = []struct {
+	statusCode string
+	response   string
+}{
+	{"500", "internal server error"},
+	{"502", "bad gateway"},
+	{"503", "service unavailable"},
+	{"504", "gateway timeout"},
+}
+
+// Latency - log-normal latency distribution with the given mean and standard
+// deviation in milliseconds, which gives the long tail of real services
+type Latency struct {
+	MeanMs   float64
+	StdDevMs float64
+}
+
+// Sample - one latency in milliseconds, at least 1
+func (l Latency) Sample(r *rand.Rand) int {
+	if l.MeanMs <= 0 {
+		return 1
+	}
+	if l.StdDevMs <= 0 {
+		return max(1, int(math.Round(l.MeanMs)))
+	}
+	variance := math.Log(1 + (l.StdDevMs*l.StdDevMs)/(l.MeanMs*l.MeanMs))
+	mu := math.Log(l.MeanMs) - variance/2
+	return max(1, int(math.Round(math.Exp(mu+math.Sqrt(variance)*r.NormFloat64()))))
+}
+
+// result - single request result in the form nodes report them, every value
+// is a string
+func result(r *rand.Rand, latency Latency, errorRate float64) string {
+	entry := map[string]string{
+		"isSuccess":  "true",
+		"latencyMs":  strconv.Itoa(latency.Sample(r)),
+		"response":   "OK",
+		"statusCode": "200",
+	}
+	if r.Float64() < errorRate {
+		failure := failures[r.Intn(len(failures))]
+		entry["isSuccess"] = "false"
+		entry["statusCode"] = failure.statusCode
+		entry["response"] = failure.response
+	}
+	bytes, _ := json.Marshal(entry)
+	return string(bytes)
+}
+
+// encodeResults - result batch of a node heartbeat, base64 of the json list of
+// results as PushLoadTestResult decodes it
+func encodeResults(results []string) string {
+	bytes, _ := json.Marshal(results)
+	return base64.StdEncoding.EncodeToString(bytes)
+}
@@ -0,0 +1,385 @@
+/*
+Package sim - simulated node groups which behave like real ones on the wire so
+the manager can be run and tried locally without generating real load
+
+	t := transport.NewInProc()
+	g := sim.NewNodeGroup(t, sim.Config{
+		NodeGroupID: "sim-1",
+		Nodes:       3,
+		Latency:     sim.Latency{MeanMs: 80, StdDevMs: 40},
+		ErrorRate:   0.01,
+	})
+	go g.Run(ctx)
+
+A simulated node group listens on its topic for start_loadtest, stop_loadtest
+and configure, and sends ng_update heartbeats with result batches of the
+requests its nodes would have made. Heartbeats can be dropped and the node
+group can crash and restart to exercise the health handling of the manager
+*/
+package sim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"github.com/mridulganga/dlt-manager/pkg/transport"
+	"github.com/mridulganga/dlt-manager/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultHeartbeatInterval - heartbeat interval of node groups which were never configured
+	DefaultHeartbeatInterval = 10 * time.Second
+	registrationTimeout      = 10 * time.Second
+	nodeVersion              = "sim"
+)
+
+// Config - behaviour of a simulated node group, zero values are the defaults
+type Config struct {
+	NodeGroupID string
+	Namespace   transport.Namespace
+
+	// Topic - topic the node group gets commands on, defaults to ng/<id> in
+	// the namespace which is the topic given on registration
+	Topic string
+
+	// Nodes - number of nodes sharing the load, defaults to 1
+	Nodes             int
+	HeartbeatInterval time.Duration
+
+	Latency   Latency
+	ErrorRate float64
+
+	// DropRate - share of heartbeats which are never sent, their results are
+	// lost like they would be with a flaky connection
+	DropRate float64
+
+	// CrashAfter - the node group crashes this long after every start and
+	// stays down for CrashFor, forgetting its load tests and config. 0 never
+	// crashes
+	CrashAfter time.Duration
+	CrashFor   time.Duration
+
+	// MaxTPS - capacity reported with the heartbeats, 0 reports none
+	MaxTPS      float64
+	MultiTenant bool
+
+	// EnrollmentToken - register with the token on every start, node groups
+	// created through the api don't need one
+	EnrollmentToken string
+
+	Seed int64
+}
+
+// loadTest - a load test the node group runs
+type loadTest struct {
+	id  string
+	tps float64
+	end time.Time
+
+	// last time results were generated and the share of a request which
+	// didn't make it into the last batch
+	generatedAt time.Time
+	carry       float64
+}
+
+// NodeGroup - simulated node group
+type NodeGroup struct {
+	cfg   Config
+	t     transport.Transport
+	nodes []string
+
+	mu            sync.Mutex
+	r             *rand.Rand
+	loadTests     []*loadTest
+	interval      time.Duration
+	configVersion int64
+	seq           int64
+}
+
+func NewNodeGroup(t transport.Transport, cfg Config) *NodeGroup {
+	if cfg.Topic == "" {
+		cfg.Topic = cfg.Namespace.Topic("ng/" + cfg.NodeGroupID)
+	}
+	if cfg.Nodes <= 0 {
+		cfg.Nodes = 1
+	}
+	if cfg.HeartbeatInterval <= 0 {
+		cfg.HeartbeatInterval = DefaultHeartbeatInterval
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	nodes := []string{}
+	for i := 1; i <= cfg.Nodes; i++ {
+		nodes = append(nodes, fmt.Sprintf("%s-node-%d", cfg.NodeGroupID, i))
+	}
+	g := &NodeGroup{
+		cfg:   cfg,
+		t:     t,
+		nodes: nodes,
+		r:     rand.New(rand.NewSource(cfg.Seed)),
+	}
+	g.reset()
+	return g
+}
+
+// Run - start the node group and send heartbeats until ctx is done
+func (g *NodeGroup) Run(ctx context.Context) error {
+	if err := g.start(); err != nil {
+		return err
+	}
+	startedAt := time.Now()
+	for {
+		timer := time.NewTimer(g.heartbeatInterval())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			g.t.Unsub(g.cfg.Topic)
+			return nil
+		case <-timer.C:
+		}
+
+		if g.cfg.CrashAfter > 0 && time.Since(startedAt) >= g.cfg.CrashAfter {
+			logrus.Warnf("sim %s crashed, down for %v", g.cfg.NodeGroupID, g.cfg.CrashFor)
+			g.t.Unsub(g.cfg.Topic)
+			g.reset()
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(g.cfg.CrashFor):
+			}
+			if err := g.start(); err != nil {
+				return err
+			}
+			startedAt = time.Now()
+			continue
+		}
+		g.heartbeat()
+	}
+}
+
+// start - register when configured to and listen for commands
+func (g *NodeGroup) start() error {
+	if g.cfg.EnrollmentToken != "" {
+		if err := g.register(); err != nil {
+			return err
+		}
+	}
+	logrus.Infof("sim %s listening on %s with %d nodes", g.cfg.NodeGroupID, g.cfg.Topic, len(g.nodes))
+	return g.t.Sub(g.cfg.Topic, g.handle)
+}
+
+// reset - forget everything like a restarted node group would
+func (g *NodeGroup) reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.loadTests = []*loadTest{}
+	g.interval = g.cfg.HeartbeatInterval
+	g.configVersion = 0
+	// sequence numbers have to keep growing across restarts, otherwise the
+	// manager takes new batches for redelivered ones
+	g.seq = time.Now().UnixMilli()
+}
+
+func (g *NodeGroup) heartbeatInterval() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.interval
+}
+
+// register - announce the node group to the manager with the enrollment token
+func (g *NodeGroup) register() error {
+	reply, err := g.t.Request(g.cfg.Namespace.Topic("manager"), map[string]any{
+		"action":           "register",
+		"ng_id":            g.cfg.NodeGroupID,
+		"nodes":            g.nodes,
+		"enrollment_token": g.cfg.EnrollmentToken,
+	}, registrationTimeout)
+	if err != nil {
+		return fmt.Errorf("error while registering %s %s", g.cfg.NodeGroupID, err.Error())
+	}
+	data := struct {
+		Status string `json:"status"`
+		Topic  string `json:"topic"`
+		Error  string `json:"error"`
+	}{}
+	if err := json.Unmarshal(reply.Payload, &data); err != nil {
+		return fmt.Errorf("error while decoding registration %s", err.Error())
+	}
+	if data.Status == "rejected" {
+		return errors.New("registration rejected " + data.Error)
+	}
+	if data.Topic != "" {
+		g.cfg.Topic = data.Topic
+	}
+	logrus.Infof("sim %s registration %s", g.cfg.NodeGroupID, data.Status)
+	return nil
+}
+
+// handle - commands sent by the manager to the node group topic
+func (g *NodeGroup) handle(msg transport.Message) {
+	data := struct {
+		Action        string             `json:"action"`
+		LoadTestID    string             `json:"load_test_id"`
+		TPS           float64            `json:"tps"`
+		Duration      int                `json:"duration"`
+		ConfigVersion int64              `json:"config_version"`
+		Config        db.NodeGroupConfig `json:"config"`
+	}{}
+	if err := json.Unmarshal(msg.Payload, &data); err != nil {
+		logrus.Errorf("sim %s error while decoding message %v", g.cfg.NodeGroupID, err.Error())
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch data.Action {
+	case "start_loadtest":
+		if g.find(data.LoadTestID) != nil {
+			return
+		}
+		// single tenant node groups give up what they ran for the new load test
+		if !g.cfg.MultiTenant {
+			g.loadTests = []*loadTest{}
+		}
+		now := time.Now()
+		g.loadTests = append(g.loadTests, &loadTest{
+			id:          data.LoadTestID,
+			tps:         data.TPS,
+			end:         now.Add(time.Duration(data.Duration) * time.Second),
+			generatedAt: now,
+		})
+		logrus.Infof("sim %s started load test %s at %v tps for %ds", g.cfg.NodeGroupID, data.LoadTestID, data.TPS, data.Duration)
+	case "stop_loadtest":
+		g.loadTests = slices.DeleteFunc(g.loadTests, func(lt *loadTest) bool {
+			return data.LoadTestID == "" || lt.id == data.LoadTestID
+		})
+		logrus.Infof("sim %s stopped load test %s", g.cfg.NodeGroupID, data.LoadTestID)
+	case "configure":
+		if data.Config.HeartbeatInterval > 0 {
+			g.interval = time.Duration(data.Config.HeartbeatInterval) * time.Second
+		}
+		g.configVersion = data.ConfigVersion
+		logrus.Infof("sim %s applied config version %d", g.cfg.NodeGroupID, data.ConfigVersion)
+	default:
+		logrus.Warnf("sim %s ignoring action %s", g.cfg.NodeGroupID, data.Action)
+	}
+}
+
+func (g *NodeGroup) find(loadTestId string) *loadTest {
+	for _, lt := range g.loadTests {
+		if lt.id == loadTestId {
+			return lt
+		}
+	}
+	return nil
+}
+
+// heartbeat - send the state of the node group with the results generated
+// since the last heartbeat, load tests which reached their duration are sent
+// one last time and then dropped
+func (g *NodeGroup) heartbeat() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now()
+
+	nodeUpdates := map[string][]db.NodeHeartBeat{}
+	active := []string{}
+	for _, lt := range g.loadTests {
+		active = append(active, lt.id)
+		for i, results := range g.generate(lt, now) {
+			g.seq++
+			nodeId := g.nodes[i]
+			nodeUpdates[nodeId] = append(nodeUpdates[nodeId], db.NodeHeartBeat{
+				Action:          "node_update",
+				IsTestActive:    "true",
+				LoadTestID:      lt.id,
+				LoadTestResults: encodeResults(results),
+				NodeID:          nodeId,
+				NodeStatus:      "running",
+				Timestamp:       now.Format(time.RFC3339Nano),
+				Sequence:        g.seq,
+				Version:         nodeVersion,
+			})
+		}
+	}
+	for _, nodeId := range g.nodes {
+		if len(nodeUpdates[nodeId]) == 0 {
+			nodeUpdates[nodeId] = []db.NodeHeartBeat{{
+				Action:       "node_update",
+				IsTestActive: "false",
+				NodeID:       nodeId,
+				NodeStatus:   "idle",
+				Timestamp:    now.Format(time.RFC3339Nano),
+				Version:      nodeVersion,
+			}}
+		}
+	}
+	g.loadTests = slices.DeleteFunc(g.loadTests, func(lt *loadTest) bool {
+		return !now.Before(lt.end)
+	})
+
+	if g.r.Float64() < g.cfg.DropRate {
+		logrus.Warnf("sim %s dropped heartbeat", g.cfg.NodeGroupID)
+		return
+	}
+
+	updates, _ := json.Marshal(nodeUpdates)
+	heartbeat := db.NGHeartbeat{
+		Action:           "ng_update",
+		NodeGroupStatus:  "healthy",
+		NodeGroupID:      g.cfg.NodeGroupID,
+		Nodes:            g.nodes,
+		IsLoadTestActive: len(active) > 0,
+		Timestamp:        now.Format(time.RFC3339Nano),
+		NodeUpdates:      string(updates),
+		ConfigVersion:    g.configVersion,
+		MultiTenant:      g.cfg.MultiTenant,
+	}
+	if len(active) > 0 {
+		heartbeat.LoadTestId = active[0]
+	}
+	if g.cfg.MultiTenant {
+		heartbeat.ActiveLoadTests = active
+	}
+	if g.cfg.MaxTPS > 0 {
+		heartbeat.Capacity = &db.NodeGroupCapacity{
+			MaxTPS:      g.cfg.MaxTPS,
+			Concurrency: len(g.nodes),
+		}
+	}
+	data := map[string]any{}
+	utils.DeepCopy(heartbeat, &data)
+	if err := g.t.Publish(g.cfg.Namespace.Topic("manager"), data); err != nil {
+		logrus.Errorf("sim %s error while sending heartbeat %v", g.cfg.NodeGroupID, err.Error())
+	}
+}
+
+// generate - results of the requests made since the last heartbeat, spread
+// over the nodes
+func (g *NodeGroup) generate(lt *loadTest, now time.Time) [][]string {
+	until := now
+	if lt.end.Before(until) {
+		until = lt.end
+	}
+	requests := lt.tps*until.Sub(lt.generatedAt).Seconds() + lt.carry
+	count := int(math.Floor(requests))
+	lt.carry = requests - float64(count)
+	lt.generatedAt = until
+
+	results := make([][]string, len(g.nodes))
+	for i := 0; i < count; i++ {
+		node := i % len(g.nodes)
+		results[node] = append(results[node], result(g.r, g.cfg.Latency, g.cfg.ErrorRate))
+	}
+	return results
+}
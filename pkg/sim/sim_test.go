@@ -0,0 +1,240 @@
+package sim
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"github.com/mridulganga/dlt-manager/pkg/transport"
+)
+
+// heartbeats - collects the heartbeats published on the manager topic
+type heartbeats struct {
+	mu    sync.Mutex
+	items []db.NGHeartbeat
+}
+
+func (h *heartbeats) handle(msg transport.Message) {
+	hb := db.NGHeartbeat{}
+	if err := json.Unmarshal(msg.Payload, &hb); err != nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.items = append(h.items, hb)
+}
+
+func (h *heartbeats) all() []db.NGHeartbeat {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]db.NGHeartbeat{}, h.items...)
+}
+
+// decodeResults - results of a heartbeat by node the way PushLoadTestResult
+// reads them
+func decodeResults(t *testing.T, hb db.NGHeartbeat) map[string][]db.LoadTestEntry {
+	t.Helper()
+	nodeUpdates := db.NodeUpdates{}
+	if err := json.Unmarshal([]byte(hb.NodeUpdates), &nodeUpdates); err != nil {
+		t.Fatalf("node updates: %v", err)
+	}
+	result := map[string][]db.LoadTestEntry{}
+	for nodeId, updates := range nodeUpdates {
+		for _, update := range updates {
+			bytes, _ := json.Marshal(update)
+			heartbeat := db.NodeHeartBeat{}
+			json.Unmarshal(bytes, &heartbeat)
+			if heartbeat.LoadTestResults == "" {
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(heartbeat.LoadTestResults)
+			if err != nil {
+				t.Fatalf("results of %s: %v", nodeId, err)
+			}
+			results := []string{}
+			if err := json.Unmarshal(decoded, &results); err != nil {
+				t.Fatalf("results of %s: %v", nodeId, err)
+			}
+			for _, res := range results {
+				entry := db.LoadTestEntry{}
+				if err := json.Unmarshal([]byte(res), &entry); err != nil {
+					t.Fatalf("result of %s: %v", nodeId, err)
+				}
+				result[nodeId] = append(result[nodeId], entry)
+			}
+		}
+	}
+	return result
+}
+
+func TestNodeGroupRunsLoadTest(t *testing.T) {
+	tr := transport.NewInProc()
+	defer tr.Close()
+	received := &heartbeats{}
+	tr.Sub("test/manager", received.handle)
+
+	g := NewNodeGroup(tr, Config{
+		NodeGroupID:       "sim-1",
+		Namespace:         "test",
+		Nodes:             2,
+		HeartbeatInterval: 50 * time.Millisecond,
+		Latency:           Latency{MeanMs: 80, StdDevMs: 40},
+		ErrorRate:         0.5,
+		Seed:              1,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go g.Run(ctx)
+
+	// wait for the subscription before starting the load test
+	time.Sleep(20 * time.Millisecond)
+	tr.Publish("test/ng/sim-1", map[string]any{
+		"action":       "start_loadtest",
+		"load_test_id": "lt-1",
+		"tps":          200,
+		"duration":     1,
+	})
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		all := received.all()
+		if len(all) > 0 && !all[len(all)-1].IsLoadTestActive && countActive(all) > 0 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+
+	total, failed := 0, 0
+	nodes := map[string]bool{}
+	for _, hb := range received.all() {
+		if hb.NodeGroupID != "sim-1" || hb.Action != "ng_update" {
+			t.Fatalf("unexpected heartbeat %+v", hb)
+		}
+		if hb.IsLoadTestActive && hb.LoadTestId != "lt-1" {
+			t.Fatalf("heartbeat of load test %s", hb.LoadTestId)
+		}
+		for nodeId, entries := range decodeResults(t, hb) {
+			nodes[nodeId] = true
+			for _, entry := range entries {
+				total++
+				if entry.IsSuccess != "true" {
+					failed++
+					if entry.StatusCode == "200" {
+						t.Fatalf("failed result with status 200")
+					}
+				}
+			}
+		}
+	}
+	// 200 tps for a second, the fractions carried between heartbeats may
+	// leave out the last request
+	if total < 199 || total > 200 {
+		t.Fatalf("got %d results, want 200", total)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("results came from %d nodes, want 2", len(nodes))
+	}
+	if failed == 0 || failed == total {
+		t.Fatalf("%d of %d results failed with an error rate of 0.5", failed, total)
+	}
+}
+
+func countActive(all []db.NGHeartbeat) int {
+	count := 0
+	for _, hb := range all {
+		if hb.IsLoadTestActive {
+			count++
+		}
+	}
+	return count
+}
+
+func TestNodeGroupStopsLoadTest(t *testing.T) {
+	tr := transport.NewInProc()
+	defer tr.Close()
+	received := &heartbeats{}
+	tr.Sub("manager", received.handle)
+
+	g := NewNodeGroup(tr, Config{NodeGroupID: "sim-1", HeartbeatInterval: 30 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go g.Run(ctx)
+
+	time.Sleep(20 * time.Millisecond)
+	tr.Publish("ng/sim-1", map[string]any{"action": "start_loadtest", "load_test_id": "lt-1", "tps": 10, "duration": 600})
+	time.Sleep(100 * time.Millisecond)
+	tr.Publish("ng/sim-1", map[string]any{"action": "stop_loadtest", "load_test_id": "lt-1"})
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	all := received.all()
+	if countActive(all) == 0 {
+		t.Fatal("load test never reported active")
+	}
+	if all[len(all)-1].IsLoadTestActive {
+		t.Fatal("load test still active after stop")
+	}
+}
+
+func TestNodeGroupAppliesConfig(t *testing.T) {
+	tr := transport.NewInProc()
+	defer tr.Close()
+	received := &heartbeats{}
+	tr.Sub("manager", received.handle)
+
+	g := NewNodeGroup(tr, Config{NodeGroupID: "sim-1", HeartbeatInterval: 20 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go g.Run(ctx)
+
+	time.Sleep(10 * time.Millisecond)
+	tr.Publish("ng/sim-1", map[string]any{"action": "configure", "config_version": 3, "config": db.NodeGroupConfig{Version: 3}})
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	all := received.all()
+	if len(all) == 0 || all[len(all)-1].ConfigVersion != 3 {
+		t.Fatalf("config version not reported, heartbeats %+v", all)
+	}
+}
+
+func TestNodeGroupDropsHeartbeats(t *testing.T) {
+	tr := transport.NewInProc()
+	defer tr.Close()
+	received := &heartbeats{}
+	tr.Sub("manager", received.handle)
+
+	g := NewNodeGroup(tr, Config{NodeGroupID: "sim-1", HeartbeatInterval: 10 * time.Millisecond, DropRate: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	g.Run(ctx)
+
+	if len(received.all()) != 0 {
+		t.Fatalf("got %d heartbeats with every heartbeat dropped", len(received.all()))
+	}
+}
+
+func TestLatencySample(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	l := Latency{MeanMs: 100, StdDevMs: 50}
+	sum := 0
+	for i := 0; i < 10000; i++ {
+		sample := l.Sample(r)
+		if sample < 1 {
+			t.Fatalf("latency %d below 1ms", sample)
+		}
+		sum += sample
+	}
+	if mean := float64(sum) / 10000; mean < 95 || mean > 105 {
+		t.Fatalf("mean latency %v, want about 100", mean)
+	}
+	if got := (Latency{MeanMs: 20}).Sample(r); got != 20 {
+		t.Fatalf("latency without deviation %d, want 20", got)
+	}
+}
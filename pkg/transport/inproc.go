@@ -0,0 +1,150 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type inProcSub struct {
+	handler Handler
+	queue   chan Message
+	done    chan struct{}
+}
+
+// InProc - channel based transport for tests and single binary setups, all
+// clients created from the same InProc share their subscriptions. Topics are
+// matched exactly, wildcards aren't supported
+type InProc struct {
+	mu     sync.RWMutex
+	subs   map[string][]*inProcSub
+	closed bool
+}
+
+func NewInProc() *InProc {
+	return &InProc{
+		subs: map[string][]*inProcSub{},
+	}
+}
+
+func (t *InProc) IsConnected() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return !t.closed
+}
+
+func (t *InProc) WaitUntilConnected() {}
+
+// Sub - every subscription gets its own queue so a slow handler doesn't block
+// the publisher, messages are delivered in order per subscription
+func (t *InProc) Sub(topic string, handler Handler) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return fmt.Errorf("transport closed")
+	}
+
+	sub := &inProcSub{
+		handler: handler,
+		queue:   make(chan Message, 256),
+		done:    make(chan struct{}),
+	}
+	t.subs[topic] = append(t.subs[topic], sub)
+
+	go func() {
+		for {
+			select {
+			case msg := <-sub.queue:
+				sub.handler(msg)
+			case <-sub.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (t *InProc) Unsub(topic string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, sub := range t.subs[topic] {
+		close(sub.done)
+	}
+	delete(t.subs, topic)
+	return nil
+}
+
+func (t *InProc) Publish(topic string, data map[string]any) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return t.publish(Message{
+		Topic:   topic,
+		Payload: jsonData,
+		ReplyTo: ReplyTo(jsonData),
+	})
+}
+
+func (t *InProc) publish(msg Message) error {
+	t.mu.RLock()
+	if t.closed {
+		t.mu.RUnlock()
+		return fmt.Errorf("transport closed")
+	}
+	subs := append([]*inProcSub{}, t.subs[msg.Topic]...)
+	t.mu.RUnlock()
+
+	// don't hold the lock while waiting on a full queue, handlers may subscribe
+	for _, sub := range subs {
+		select {
+		case sub.queue <- msg:
+		case <-sub.done:
+		}
+	}
+	return nil
+}
+
+func (t *InProc) Request(topic string, data map[string]any, timeout time.Duration) (Message, error) {
+	replyTo := fmt.Sprintf("%s/reply/%s", topic, uuid.New().String())
+	replies := make(chan Message, 1)
+	err := t.Sub(replyTo, func(msg Message) {
+		select {
+		case replies <- msg:
+		default:
+		}
+	})
+	if err != nil {
+		return Message{}, err
+	}
+	defer t.Unsub(replyTo)
+
+	if err := t.Publish(topic, WithReplyTo(data, replyTo)); err != nil {
+		return Message{}, err
+	}
+
+	select {
+	case msg := <-replies:
+		return msg, nil
+	case <-time.After(timeout):
+		return Message{}, ErrRequestTimeout
+	}
+}
+
+func (t *InProc) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	for _, subs := range t.subs {
+		for _, sub := range subs {
+			close(sub.done)
+		}
+	}
+	t.subs = map[string][]*inProcSub{}
+	t.closed = true
+}
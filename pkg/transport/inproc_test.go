@@ -0,0 +1,114 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func receive(t *testing.T, ch <-chan Message) Message {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+	}
+	return Message{}
+}
+
+func TestInProcPublishSub(t *testing.T) {
+	tr := NewInProc()
+	defer tr.Close()
+
+	first, second := make(chan Message, 10), make(chan Message, 10)
+	tr.Sub("topic", func(msg Message) { first <- msg })
+	tr.Sub("topic", func(msg Message) { second <- msg })
+	tr.Sub("other", func(msg Message) { t.Errorf("message on other topic %s", msg.Payload) })
+
+	for i := 0; i < 3; i++ {
+		if err := tr.Publish("topic", map[string]any{"seq": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// every subscriber gets every message in order
+	for _, ch := range []chan Message{first, second} {
+		for i := 0; i < 3; i++ {
+			msg := receive(t, ch)
+			data := map[string]int{}
+			json.Unmarshal(msg.Payload, &data)
+			if msg.Topic != "topic" || data["seq"] != i {
+				t.Fatalf("got %s on %s, want seq %d", msg.Payload, msg.Topic, i)
+			}
+		}
+	}
+}
+
+func TestInProcUnsub(t *testing.T) {
+	tr := NewInProc()
+	defer tr.Close()
+
+	ch := make(chan Message, 10)
+	tr.Sub("topic", func(msg Message) { ch <- msg })
+	tr.Unsub("topic")
+	tr.Publish("topic", map[string]any{})
+
+	select {
+	case msg := <-ch:
+		t.Fatalf("got %s after unsubscribing", msg.Payload)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestInProcRequest(t *testing.T) {
+	tr := NewInProc()
+	defer tr.Close()
+
+	tr.Sub("ns/manager", func(msg Message) {
+		if msg.ReplyTo == "" {
+			t.Errorf("request without reply topic %s", msg.Payload)
+			return
+		}
+		tr.Publish(msg.ReplyTo, map[string]any{"status": "ok"})
+	})
+
+	reply, err := tr.Request("ns/manager", map[string]any{"action": "ping"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]string{}
+	json.Unmarshal(reply.Payload, &data)
+	if data["status"] != "ok" {
+		t.Fatalf("got reply %s", reply.Payload)
+	}
+	// the reply topic stays inside the namespace of the request
+	if !Namespace("ns").Contains(reply.Topic) {
+		t.Fatalf("reply on %s outside the namespace", reply.Topic)
+	}
+}
+
+func TestInProcRequestTimeout(t *testing.T) {
+	tr := NewInProc()
+	defer tr.Close()
+
+	_, err := tr.Request("nobody", map[string]any{}, 20*time.Millisecond)
+	if !errors.Is(err, ErrRequestTimeout) {
+		t.Fatalf("got %v, want ErrRequestTimeout", err)
+	}
+}
+
+func TestInProcClosed(t *testing.T) {
+	tr := NewInProc()
+	tr.Close()
+
+	if tr.IsConnected() {
+		t.Fatal("closed transport reports connected")
+	}
+	if err := tr.Publish("topic", map[string]any{}); err == nil {
+		t.Fatal("publish on closed transport succeeded")
+	}
+	if err := tr.Sub("topic", func(Message) {}); err == nil {
+		t.Fatal("sub on closed transport succeeded")
+	}
+}
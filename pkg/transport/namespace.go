@@ -0,0 +1,79 @@
+package transport
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Namespace - topic prefix which keeps managers and node groups of different
+// environments apart when they share a broker, the empty namespace is no prefix
+type Namespace string
+
+// ParseNamespaces - comma separated list of namespaces, no namespaces means the
+// manager works on bare topics
+func ParseNamespaces(value string) []Namespace {
+	namespaces := []Namespace{}
+	for _, ns := range strings.Split(value, ",") {
+		ns = strings.Trim(strings.TrimSpace(ns), "/")
+		if ns != "" {
+			namespaces = append(namespaces, Namespace(ns))
+		}
+	}
+	if len(namespaces) == 0 {
+		namespaces = append(namespaces, Namespace(""))
+	}
+	return namespaces
+}
+
+// Topic - topic inside the namespace
+func (n Namespace) Topic(topic string) string {
+	if n == "" {
+		return topic
+	}
+	return string(n) + "/" + topic
+}
+
+// Contains - whether the topic lies inside the namespace
+func (n Namespace) Contains(topic string) bool {
+	if n == "" {
+		return true
+	}
+	return strings.HasPrefix(topic, string(n)+"/") && len(topic) > len(n)+1
+}
+
+// ValidateTopic - check that a node group topic can be published to from the namespace
+func (n Namespace) ValidateTopic(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("topic is required")
+	}
+	if strings.ContainsAny(topic, "+#*>") {
+		return fmt.Errorf("topic %s must not contain wildcards", topic)
+	}
+	if !n.Contains(topic) {
+		return fmt.Errorf("topic %s is outside namespace %s", topic, n)
+	}
+	return nil
+}
+
+// NamespaceOf - the most specific namespace out of namespaces which contains topic
+func NamespaceOf(topic string, namespaces []Namespace) (Namespace, bool) {
+	found := false
+	result := Namespace("")
+	for _, ns := range namespaces {
+		if ns.Contains(topic) && (!found || len(ns) > len(result)) {
+			result = ns
+			found = true
+		}
+	}
+	return result, found
+}
+
+// HasNamespace - whether ns is one of namespaces
+func HasNamespace(namespaces []Namespace, ns Namespace) bool {
+	for _, n := range namespaces {
+		if n == ns {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package transport
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseNamespaces(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []Namespace
+	}{
+		{"", []Namespace{""}},
+		{" , ", []Namespace{""}},
+		{"prod", []Namespace{"prod"}},
+		{"prod, /staging/ ,dev", []Namespace{"prod", "staging", "dev"}},
+	}
+	for _, tt := range tests {
+		if got := ParseNamespaces(tt.value); !slices.Equal(got, tt.want) {
+			t.Errorf("ParseNamespaces(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestNamespaceTopic(t *testing.T) {
+	if got := Namespace("").Topic("manager"); got != "manager" {
+		t.Errorf("bare namespace topic %s", got)
+	}
+	if got := Namespace("prod").Topic("manager"); got != "prod/manager" {
+		t.Errorf("prod namespace topic %s", got)
+	}
+}
+
+func TestNamespaceContains(t *testing.T) {
+	tests := []struct {
+		ns    Namespace
+		topic string
+		want  bool
+	}{
+		{"", "anything", true},
+		{"", "prod/manager", true},
+		{"prod", "prod/manager", true},
+		{"prod", "prod/ng/1", true},
+		{"prod", "prod/", false},
+		{"prod", "prod", false},
+		{"prod", "production/manager", false},
+		{"prod", "staging/manager", false},
+		{"prod", "manager", false},
+	}
+	for _, tt := range tests {
+		if got := tt.ns.Contains(tt.topic); got != tt.want {
+			t.Errorf("Namespace(%q).Contains(%q) = %v, want %v", tt.ns, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestNamespaceValidateTopic(t *testing.T) {
+	tests := []struct {
+		ns    Namespace
+		topic string
+		valid bool
+	}{
+		{"prod", "prod/ng/1", true},
+		{"", "ng/1", true},
+		{"prod", "", false},
+		{"prod", "prod/ng/+", false},
+		{"prod", "prod/#", false},
+		{"prod", "prod/ng.*", false},
+		{"prod", "prod/ng.>", false},
+		{"prod", "staging/ng/1", false},
+	}
+	for _, tt := range tests {
+		err := tt.ns.ValidateTopic(tt.topic)
+		if (err == nil) != tt.valid {
+			t.Errorf("Namespace(%q).ValidateTopic(%q) = %v, want valid %v", tt.ns, tt.topic, err, tt.valid)
+		}
+	}
+}
+
+func TestNamespaceOf(t *testing.T) {
+	namespaces := []Namespace{"", "prod", "prod/eu"}
+	tests := []struct {
+		topic string
+		want  Namespace
+	}{
+		{"manager", ""},
+		{"prod/manager", "prod"},
+		{"prod/eu/manager", "prod/eu"},
+		{"staging/manager", ""},
+	}
+	for _, tt := range tests {
+		got, ok := NamespaceOf(tt.topic, namespaces)
+		if !ok || got != tt.want {
+			t.Errorf("NamespaceOf(%q) = %q %v, want %q", tt.topic, got, ok, tt.want)
+		}
+	}
+	if _, ok := NamespaceOf("staging/manager", []Namespace{"prod"}); ok {
+		t.Error("topic outside every namespace was found")
+	}
+}
@@ -0,0 +1,144 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+)
+
+type Nats struct {
+	conn *nats.Conn
+
+	mu   sync.Mutex
+	subs map[string][]*nats.Subscription
+}
+
+// NewNats - connect to the nats server at url, reconnects are handled by the
+// nats client
+func NewNats(url string) (*Nats, error) {
+	conn, err := nats.Connect(url,
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			logrus.Errorf("nats disconnected %v", err)
+		}),
+		nats.ReconnectHandler(func(_ *nats.Conn) {
+			logrus.Info("nats reconnected")
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error while connecting to nats %s", err.Error())
+	}
+	return &Nats{
+		conn: conn,
+		subs: map[string][]*nats.Subscription{},
+	}, nil
+}
+
+// NewEmbeddedNatsServer - start a nats server inside the current process, port
+// -1 picks a random free port. Use ClientURL() on the result to connect
+func NewEmbeddedNatsServer(host string, port int) (*server.Server, error) {
+	ns, err := server.NewServer(&server.Options{
+		Host:   host,
+		Port:   port,
+		NoLog:  true,
+		NoSigs: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error while creating nats server %s", err.Error())
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(10 * time.Second) {
+		ns.Shutdown()
+		return nil, fmt.Errorf("nats server not ready")
+	}
+	return ns, nil
+}
+
+func (t *Nats) IsConnected() bool {
+	return t.conn.IsConnected()
+}
+
+func (t *Nats) WaitUntilConnected() {
+	for !t.conn.IsConnected() {
+		time.Sleep(time.Second)
+	}
+}
+
+func (t *Nats) Sub(topic string, handler Handler) error {
+	sub, err := t.conn.Subscribe(topic, func(m *nats.Msg) {
+		handler(Message{
+			Topic:   m.Subject,
+			Payload: m.Data,
+			ReplyTo: m.Reply,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.subs[topic] = append(t.subs[topic], sub)
+	logrus.Infof("Subscribed to topic: %s", topic)
+	return nil
+}
+
+func (t *Nats) Unsub(topic string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, sub := range t.subs[topic] {
+		if err := sub.Unsubscribe(); err != nil {
+			return err
+		}
+	}
+	delete(t.subs, topic)
+	return nil
+}
+
+func (t *Nats) Publish(topic string, data map[string]any) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return t.conn.Publish(topic, jsonData)
+}
+
+// Request - the reply topic lies under the requested topic like with the other
+// transports, so that it stays inside the namespace of the request
+func (t *Nats) Request(topic string, data map[string]any, timeout time.Duration) (Message, error) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return Message{}, err
+	}
+	replyTo := fmt.Sprintf("%s/reply/%s", topic, uuid.New().String())
+	sub, err := t.conn.SubscribeSync(replyTo)
+	if err != nil {
+		return Message{}, err
+	}
+	defer sub.Unsubscribe()
+
+	if err := t.conn.PublishRequest(topic, replyTo, jsonData); err != nil {
+		return Message{}, err
+	}
+	m, err := sub.NextMsg(timeout)
+	if err == nats.ErrTimeout {
+		return Message{}, ErrRequestTimeout
+	}
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Topic:   m.Subject,
+		Payload: m.Data,
+	}, nil
+}
+
+func (t *Nats) Close() {
+	t.conn.Close()
+}
@@ -0,0 +1,47 @@
+package transport
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNatsRequest(t *testing.T) {
+	server, err := NewEmbeddedNatsServer("127.0.0.1", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Shutdown()
+
+	responder, err := NewNats(server.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer responder.Close()
+	requester, err := NewNats(server.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer requester.Close()
+
+	replyTopics := make(chan string, 1)
+	responder.Sub("ns/manager", func(msg Message) {
+		replyTopics <- msg.ReplyTo
+		responder.Publish(msg.ReplyTo, map[string]any{"status": "ok"})
+	})
+	responder.conn.Flush()
+
+	reply, err := requester.Request("ns/manager", map[string]any{"action": "ping"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]string{}
+	json.Unmarshal(reply.Payload, &data)
+	if data["status"] != "ok" {
+		t.Fatalf("got reply %s", reply.Payload)
+	}
+	// replies stay inside the namespace like with the other transports
+	if replyTo := <-replyTopics; !Namespace("ns").Contains(replyTo) {
+		t.Fatalf("reply topic %s outside the namespace", replyTo)
+	}
+}
@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ReplyToKey - field added to the published data by Request on transports which
+// don't support replies natively, responders publish their answer to it
+const ReplyToKey = "reply_to"
+
+var ErrRequestTimeout = errors.New("request timed out")
+
+type Message struct {
+	Topic   string
+	Payload []byte
+	ReplyTo string
+}
+
+type Handler func(msg Message)
+
+/*
+Transport - messaging used between the manager and the node groups
+
+	t.Sub("topic", func(msg transport.Message) {
+	        fmt.Println("Received " + string(msg.Payload))
+	        if msg.ReplyTo != "" {
+	                t.Publish(msg.ReplyTo, map[string]any{"status": "ok"})
+	        }
+	})
+
+	t.Publish("topic", map[string]any{"action": "hello"})
+
+	reply, err := t.Request("topic", map[string]any{"action": "ping"}, 5*time.Second)
+*/
+type Transport interface {
+	Publish(topic string, data map[string]any) error
+	Sub(topic string, handler Handler) error
+	Unsub(topic string) error
+	Request(topic string, data map[string]any, timeout time.Duration) (Message, error)
+	IsConnected() bool
+	WaitUntilConnected()
+	Close()
+}
+
+// ReplyTo - read the reply topic from a json payload published by Request
+func ReplyTo(payload []byte) string {
+	data := struct {
+		ReplyTo string `json:"reply_to"`
+	}{}
+	json.Unmarshal(payload, &data)
+	return data.ReplyTo
+}
+
+// WithReplyTo - copy of data with the reply topic set
+func WithReplyTo(data map[string]any, replyTo string) map[string]any {
+	result := map[string]any{}
+	for k, v := range data {
+		result[k] = v
+	}
+	result[ReplyToKey] = replyTo
+	return result
+}
@@ -0,0 +1,91 @@
+package view
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/auth"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CreateAPIKey - the key is only returned here, afterwards just its prefix is known
+func (v View) CreateAPIKey(c *gin.Context) {
+	req := api.CreateAPIKeyRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+
+	user := auth.CurrentUser(c)
+	if !user.HasRole(req.Scope) {
+		apierr.Respond(c, apierr.Forbidden("scope can't exceed your own role"))
+		return
+	}
+	if !req.ExpiresAt.After(time.Now()) {
+		apierr.Respond(c, apierr.Invalid("expires_at", "must be in the future"))
+		return
+	}
+
+	key, keyHash, err := auth.GenerateAPIKey()
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	result, err := v.d.CreateAPIKey(&db.APIKey{
+		Name:      req.Name,
+		UserID:    user.ID,
+		Prefix:    key[:12],
+		KeyHash:   keyHash,
+		Scope:     req.Scope,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Create, audit.APIKey, result.ID, nil, result)
+	c.JSON(200, map[string]any{"key": key, "api_key": result})
+}
+
+// ListAPIKeys - own keys, admins see the keys of all users
+func (v View) ListAPIKeys(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	var results *[]db.APIKey
+	var err error
+	if user.HasRole(db.RoleAdmin) {
+		results, err = v.d.ListAPIKey()
+	} else {
+		results, err = v.d.ListAPIKeyByUser(user.ID)
+	}
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	c.JSON(200, results)
+}
+
+func (v View) RevokeAPIKey(c *gin.Context) {
+	id := c.Param("id")
+	apiKey, err := v.d.GetAPIKeyByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	user := auth.CurrentUser(c)
+	if apiKey.UserID != user.ID && !user.HasRole(db.RoleAdmin) {
+		apierr.Respond(c, apierr.Forbidden("only admins can revoke keys of other users"))
+		return
+	}
+
+	result, err := v.d.UpdateAPIKey(id, bson.M{"revoked_at": time.Now()})
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Revoke, audit.APIKey, id, apiKey, result)
+	c.JSON(200, result)
+}
@@ -0,0 +1,43 @@
+package view
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+)
+
+// ListAudit - filter with the actor, action, target_type, target_id, from and
+// to (RFC3339) query params, at most limit entries (default 100) newest first
+func (v View) ListAudit(c *gin.Context) {
+	filter := db.AuditFilter{
+		Actor:      c.Query("actor"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		Limit:      100,
+	}
+	var err error
+	if filter.From, err = timeQuery(c, "from"); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if filter.To, err = timeQuery(c, "to"); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if filter.Limit, err = strconv.ParseInt(limit, 10, 64); err != nil || filter.Limit <= 0 {
+			apierr.Respond(c, apierr.BadRequest("invalid limit %s", limit))
+			return
+		}
+	}
+
+	results, err := v.d.ListAuditEntry(filter)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	c.JSON(200, results)
+}
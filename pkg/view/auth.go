@@ -0,0 +1,114 @@
+package view
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/auth"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var errEmailRegistered = apierr.New(409, apierr.CodeAlreadyExists, "email already registered")
+
+func (v View) Register(c *gin.Context) {
+	req := api.RegisterRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+
+	email := auth.NormalizeEmail(req.Email)
+	if _, err := v.d.GetUserByEmail(email); err == nil {
+		apierr.Respond(c, errEmailRegistered)
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	count, err := v.d.CountUser()
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	result, err := v.d.CreateUser(&db.User{
+		Name:         req.Name,
+		Email:        email,
+		PasswordHash: hash,
+		Role:         db.RoleViewer,
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		// registered concurrently, the unique email index rejected it
+		apierr.Respond(c, errEmailRegistered)
+		return
+	}
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	// the first user to register administers the manager, users registering
+	// at the same time race for the claim
+	if count == 0 {
+		claimed, err := v.d.ClaimFirstAdmin(result.ID)
+		if err != nil {
+			apierr.Respond(c, err)
+			return
+		}
+		if claimed {
+			result, err = v.d.UpdateUser(result.ID, bson.M{"role": db.RoleAdmin})
+			if err != nil {
+				apierr.Respond(c, err)
+				return
+			}
+		}
+	}
+	v.au.Record(db.AuditEntry{
+		Actor:      result.ID,
+		ActorType:  db.ActorTypeUser,
+		Action:     audit.Register,
+		TargetType: audit.User,
+		TargetID:   result.ID,
+	}, nil, result)
+	c.JSON(200, result)
+}
+
+func (v View) Login(c *gin.Context) {
+	req := api.LoginRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+
+	token, user, err := v.a.Login(req.Email, req.Password)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.au.Record(db.AuditEntry{
+		Actor:      user.ID,
+		ActorType:  db.ActorTypeUser,
+		Action:     audit.Login,
+		TargetType: audit.User,
+		TargetID:   user.ID,
+	}, nil, nil)
+	c.JSON(200, map[string]any{"token": token, "user": user})
+}
+
+func (v View) Logout(c *gin.Context) {
+	err := v.a.Logout(auth.CurrentSession(c))
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Logout, audit.User, auth.CurrentUser(c).ID, nil, nil)
+	c.JSON(200, map[string]string{"status": "ok"})
+}
+
+func (v View) Me(c *gin.Context) {
+	c.JSON(200, auth.CurrentUser(c))
+}
@@ -0,0 +1,58 @@
+package view
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/auth"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+)
+
+func configStatus(ng *db.NodeGroup) api.NodeGroupConfigStatus {
+	return api.NodeGroupConfigStatus{
+		NodeGroupID:    ng.ID,
+		Desired:        ng.Config,
+		AppliedVersion: ng.AppliedConfigVersion,
+		InSync:         ng.ConfigInSync(),
+	}
+}
+
+func (v View) GetNodeGroupConfig(c *gin.Context) {
+	ng, err := v.d.GetNodeGroupByID(c.Param("id"))
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if ng.ProjectID != currentProject(c).ID && !ng.Shared {
+		apierr.Respond(c, apierr.Forbidden("node group belongs to another project"))
+		return
+	}
+	c.JSON(200, configStatus(ng))
+}
+
+// SetNodeGroupConfig - replace the desired config of a node group and push it
+// with a configure action, node groups which are offline get it when they
+// come back
+func (v View) SetNodeGroupConfig(c *gin.Context) {
+	req := api.SetNodeGroupConfigRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	current, ok := v.ownNodeGroup(c)
+	if !ok {
+		return
+	}
+
+	config := req.Config()
+	config.UpdatedBy = auth.CurrentUser(c).ID
+	result, err := v.d.SetNodeGroupConfig(current.ID, config)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Configure, audit.NodeGroup, current.ID, current.Config, result.Config)
+	v.p.PushConfig(*result)
+	c.JSON(200, configStatus(result))
+}
@@ -0,0 +1,79 @@
+package view
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// take node groups out of rotation for maintenance without losing them
+
+// CordonNodeGroup - the node group gets no new load tests, the ones it runs
+// carry on
+func (v View) CordonNodeGroup(c *gin.Context) {
+	v.setCordon(c, audit.Cordon, bson.M{"cordoned": true})
+}
+
+func (v View) UncordonNodeGroup(c *gin.Context) {
+	v.setCordon(c, audit.Uncordon, bson.M{"cordoned": false, "draining": false})
+}
+
+// DrainNodeGroup - stop the load tests the node group runs and cordon it, it
+// stays draining until a heartbeat shows no active load test
+func (v View) DrainNodeGroup(c *gin.Context) {
+	current, ok := v.ownNodeGroup(c)
+	if !ok {
+		return
+	}
+
+	loadtests, err := v.d.ListRunningLoadTestForNodeGroup(*current)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	for _, lt := range *loadtests {
+		v.m.Publish(current.Topic, map[string]any{
+			"action":       "stop_loadtest",
+			"load_test_id": lt.ID,
+		})
+	}
+
+	result, err := v.d.UpdateNodeGroup(current.ID, bson.M{"cordoned": true, "draining": len(*loadtests) > 0})
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Drain, audit.NodeGroup, current.ID, current, result)
+	c.JSON(200, result)
+}
+
+func (v View) setCordon(c *gin.Context, action string, update bson.M) {
+	current, ok := v.ownNodeGroup(c)
+	if !ok {
+		return
+	}
+	result, err := v.d.UpdateNodeGroup(current.ID, update)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, action, audit.NodeGroup, current.ID, current, result)
+	c.JSON(200, result)
+}
+
+// ownNodeGroup - the node group of the id param when it belongs to the current
+// project, responds with the error otherwise
+func (v View) ownNodeGroup(c *gin.Context) (*db.NodeGroup, bool) {
+	current, err := v.d.GetNodeGroupByID(c.Param("id"))
+	if err != nil {
+		apierr.Respond(c, err)
+		return nil, false
+	}
+	if current.ProjectID != currentProject(c).ID {
+		apierr.Respond(c, apierr.Forbidden("node group belongs to another project"))
+		return nil, false
+	}
+	return current, true
+}
@@ -0,0 +1,92 @@
+package view
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/transport"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (v View) ListDeadLetters(c *gin.Context) {
+	results, err := v.d.ListDeadLetter()
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	c.JSON(200, results)
+}
+
+func (v View) GetDeadLetter(c *gin.Context) {
+	id := c.Param("id")
+	result, err := v.d.GetDeadLetterByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	c.JSON(200, result)
+}
+
+// ReplayDeadLetter - run the stored payload through the heartbeat processor again,
+// the dead letter is removed when processing succeeds
+func (v View) ReplayDeadLetter(c *gin.Context) {
+	id := c.Param("id")
+	deadLetter, err := v.d.GetDeadLetterByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+
+	if err := v.p.Process(transport.Message{
+		Topic:   deadLetter.Topic,
+		Payload: []byte(deadLetter.Payload),
+	}); err != nil {
+		result, updateErr := v.d.UpdateDeadLetter(id, bson.M{
+			"error":          err.Error(),
+			"replay_count":   deadLetter.ReplayCount + 1,
+			"last_replay_at": time.Now(),
+		})
+		if updateErr != nil {
+			apierr.Respond(c, updateErr)
+			return
+		}
+		v.audit(c, audit.Replay, audit.DeadLetter, id, deadLetter, result)
+		apierr.Respond(c, apierr.Unprocessable("replay failed %s", err.Error()))
+		return
+	}
+
+	if err := v.d.DeleteDeadLetter(id); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Replay, audit.DeadLetter, id, deadLetter, nil)
+	c.JSON(200, map[string]string{"status": "replayed"})
+}
+
+func (v View) DeleteDeadLetter(c *gin.Context) {
+	id := c.Param("id")
+	current, err := v.d.GetDeadLetterByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	err = v.d.DeleteDeadLetter(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Delete, audit.DeadLetter, id, current, nil)
+	c.JSON(200, map[string]string{"status": "ok"})
+}
+
+func (v View) PurgeDeadLetters(c *gin.Context) {
+	count, err := v.d.PurgeDeadLetters()
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Purge, audit.DeadLetter, "", nil, map[string]any{"deleted": count})
+	c.JSON(200, map[string]any{"status": "ok", "deleted": count})
+}
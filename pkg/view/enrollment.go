@@ -0,0 +1,120 @@
+package view
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/auth"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CreateEnrollmentToken - the token is only returned here, node groups send it
+// with their register message to join the project of the token
+func (v View) CreateEnrollmentToken(c *gin.Context) {
+	req := api.CreateEnrollmentTokenRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if !req.ExpiresAt.After(time.Now()) {
+		apierr.Respond(c, apierr.Invalid("expires_at", "must be in the future"))
+		return
+	}
+	if _, err := v.d.GetProjectByID(req.ProjectID); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if req.Namespace != "" {
+		if _, err := v.namespace(req.Namespace); err != nil {
+			apierr.Respond(c, err)
+			return
+		}
+	}
+
+	token, tokenHash, err := auth.GenerateEnrollmentToken()
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	result, err := v.d.CreateEnrollmentToken(&db.EnrollmentToken{
+		Name:        req.Name,
+		Prefix:      token[:12],
+		TokenHash:   tokenHash,
+		ProjectID:   req.ProjectID,
+		Namespace:   req.Namespace,
+		Shared:      req.Shared,
+		AutoApprove: req.AutoApprove,
+		CreatedBy:   auth.CurrentUser(c).ID,
+		ExpiresAt:   req.ExpiresAt,
+	})
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Create, audit.Enrollment, result.ID, nil, result)
+	c.JSON(200, map[string]any{"token": token, "enrollment_token": result})
+}
+
+func (v View) ListEnrollmentTokens(c *gin.Context) {
+	results, err := v.d.ListEnrollmentToken()
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	c.JSON(200, results)
+}
+
+// RevokeEnrollmentToken - node groups which already registered with the token
+// stay registered
+func (v View) RevokeEnrollmentToken(c *gin.Context) {
+	id := c.Param("id")
+	current, err := v.d.GetEnrollmentTokenByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	result, err := v.d.UpdateEnrollmentToken(id, bson.M{"revoked_at": time.Now()})
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Revoke, audit.Enrollment, id, current, result)
+	c.JSON(200, result)
+}
+
+// ApproveNodeGroup - let a node group which registered with a token without
+// auto approve take part in load tests
+func (v View) ApproveNodeGroup(c *gin.Context) {
+	id := c.Param("id")
+	current, err := v.d.GetNodeGroupByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if current.ProjectID != currentProject(c).ID {
+		apierr.Respond(c, apierr.Forbidden("node group belongs to another project"))
+		return
+	}
+	if !current.Pending {
+		apierr.Respond(c, apierr.Conflict("node group is already approved"))
+		return
+	}
+
+	result, err := v.d.UpdateNodeGroup(id, bson.M{"pending": false})
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Approve, audit.NodeGroup, id, current, result)
+	v.m.Publish(result.Topic, map[string]any{
+		"action": "registration",
+		"status": "approved",
+		"ng_id":  result.ID,
+		"topic":  result.Topic,
+	})
+	c.JSON(200, result)
+}
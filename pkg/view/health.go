@@ -0,0 +1,150 @@
+package view
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultHealthRange = 7 * 24 * time.Hour
+
+	// healthUnknown - before the first heartbeat of a node group
+	healthUnknown = "unknown"
+)
+
+// GetNodeGroupHealth - health timeline and uptime of a node group between the
+// from and to query params, the last 7 days by default
+func (v View) GetNodeGroupHealth(c *gin.Context) {
+	id := c.Param("id")
+	ng, err := v.d.GetNodeGroupByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if ng.ProjectID != currentProject(c).ID && !ng.Shared {
+		apierr.Respond(c, apierr.Forbidden("node group belongs to another project"))
+		return
+	}
+
+	from, err := timeQuery(c, "from")
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	to, err := timeQuery(c, "to")
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	// the future has no health yet
+	if now := time.Now(); to.IsZero() || to.After(now) {
+		to = now
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultHealthRange)
+	}
+	if !from.Before(to) {
+		apierr.Respond(c, apierr.BadRequest("from must be before to"))
+		return
+	}
+
+	initial := healthUnknown
+	before, err := v.d.GetHealthEventBefore(id, from)
+	if err == nil {
+		initial = before.To
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		apierr.Respond(c, err)
+		return
+	}
+	events, err := v.d.ListHealthEvent(id, from, to)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	// node groups created before the health history only have transitions,
+	// the status before the first one is still known
+	if initial == healthUnknown && len(*events) > 0 && (*events)[0].From != "" {
+		initial = (*events)[0].From
+	}
+	loadtests, err := v.d.ListLoadTestOverlapping(*ng, from, to)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+
+	timeline := healthTimeline(initial, *events, from, to)
+	for i, period := range timeline {
+		if period.Status == db.HealthStale || period.Status == db.HealthUnhealthy {
+			timeline[i].LoadTests = overlappingLoadTests(*loadtests, period)
+		}
+	}
+	c.JSON(200, api.NodeGroupHealthReport{
+		NodeGroupID:   id,
+		From:          from,
+		To:            to,
+		UptimePercent: uptime(timeline),
+		Timeline:      timeline,
+	})
+}
+
+// healthTimeline - periods between from and to starting with the initial
+// status, events which don't change the status extend the current period
+func healthTimeline(initial string, events []db.HealthEvent, from time.Time, to time.Time) []api.HealthPeriod {
+	timeline := []api.HealthPeriod{}
+	current := api.HealthPeriod{Status: initial, Start: from}
+	for _, event := range events {
+		if event.To == current.Status {
+			continue
+		}
+		if event.Timestamp.After(current.Start) {
+			current.End = event.Timestamp
+			timeline = append(timeline, current)
+		}
+		current = api.HealthPeriod{Status: event.To, Start: event.Timestamp}
+	}
+	current.End = to
+	return append(timeline, current)
+}
+
+func uptime(timeline []api.HealthPeriod) *float64 {
+	var known, healthy time.Duration
+	for _, period := range timeline {
+		if period.Status == healthUnknown {
+			continue
+		}
+		known += period.End.Sub(period.Start)
+		if period.Status == db.HealthHealthy {
+			healthy += period.End.Sub(period.Start)
+		}
+	}
+	if known == 0 {
+		return nil
+	}
+	percent := float64(healthy) / float64(known) * 100
+	return &percent
+}
+
+// overlappingLoadTests - load tests without an end time ran for their duration
+// or are still running
+func overlappingLoadTests(loadtests []db.LoadTest, period api.HealthPeriod) []string {
+	ids := []string{}
+	for _, lt := range loadtests {
+		end := lt.EndTime
+		if end.IsZero() {
+			end = lt.StartTime.Add(time.Duration(lt.Duration) * time.Second)
+			if lt.IsRunning() {
+				end = time.Now()
+			}
+		}
+		if lt.StartTime.Before(period.End) && end.After(period.Start) {
+			ids = append(ids, lt.ID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,152 @@
+package view
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+)
+
+var t0 = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(hours float64) time.Time {
+	return t0.Add(time.Duration(hours * float64(time.Hour)))
+}
+
+func event(hours float64, from string, to string) db.HealthEvent {
+	return db.HealthEvent{From: from, To: to, Timestamp: at(hours)}
+}
+
+func TestHealthTimeline(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial string
+		events  []db.HealthEvent
+		want    []api.HealthPeriod
+	}{
+		{
+			name:    "no events",
+			initial: db.HealthHealthy,
+			want:    []api.HealthPeriod{{Status: db.HealthHealthy, Start: at(0), End: at(10)}},
+		},
+		{
+			name:    "first heartbeat ends the unknown period",
+			initial: healthUnknown,
+			events:  []db.HealthEvent{event(2, "", db.HealthHealthy)},
+			want: []api.HealthPeriod{
+				{Status: healthUnknown, Start: at(0), End: at(2)},
+				{Status: db.HealthHealthy, Start: at(2), End: at(10)},
+			},
+		},
+		{
+			name:    "outage",
+			initial: db.HealthHealthy,
+			events: []db.HealthEvent{
+				event(4, db.HealthHealthy, db.HealthStale),
+				event(5, db.HealthStale, db.HealthUnhealthy),
+				// repeated events extend the period
+				event(6, db.HealthStale, db.HealthUnhealthy),
+				event(7, db.HealthUnhealthy, db.HealthHealthy),
+			},
+			want: []api.HealthPeriod{
+				{Status: db.HealthHealthy, Start: at(0), End: at(4)},
+				{Status: db.HealthStale, Start: at(4), End: at(5)},
+				{Status: db.HealthUnhealthy, Start: at(5), End: at(7)},
+				{Status: db.HealthHealthy, Start: at(7), End: at(10)},
+			},
+		},
+		{
+			name:    "event at the start replaces the initial status",
+			initial: db.HealthUnhealthy,
+			events:  []db.HealthEvent{event(0, db.HealthUnhealthy, db.HealthHealthy)},
+			want:    []api.HealthPeriod{{Status: db.HealthHealthy, Start: at(0), End: at(10)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := healthTimeline(tt.initial, tt.events, at(0), at(10))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("healthTimeline() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUptime(t *testing.T) {
+	week := 7 * 24.0
+	tests := []struct {
+		name     string
+		timeline []api.HealthPeriod
+		want     *float64
+	}{
+		{
+			name:     "never known",
+			timeline: []api.HealthPeriod{{Status: healthUnknown, Start: at(0), End: at(10)}},
+			want:     nil,
+		},
+		{
+			name: "unknown time doesn't count",
+			timeline: []api.HealthPeriod{
+				{Status: healthUnknown, Start: at(0), End: at(5)},
+				{Status: db.HealthHealthy, Start: at(5), End: at(10)},
+			},
+			want: ptr(100.0),
+		},
+		{
+			name: "stale counts as down",
+			timeline: []api.HealthPeriod{
+				{Status: db.HealthHealthy, Start: at(0), End: at(3)},
+				{Status: db.HealthStale, Start: at(3), End: at(4)},
+			},
+			want: ptr(75.0),
+		},
+		{
+			name: "an hour down in a week",
+			timeline: []api.HealthPeriod{
+				{Status: db.HealthHealthy, Start: at(0), End: at(week - 1)},
+				{Status: db.HealthUnhealthy, Start: at(week - 1), End: at(week)},
+			},
+			want: ptr((week - 1) / week * 100),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := uptime(tt.timeline)
+			if (got == nil) != (tt.want == nil) || got != nil && math.Abs(*got-*tt.want) > 1e-9 {
+				t.Errorf("uptime() = %v, want %v", deref(got), deref(tt.want))
+			}
+		})
+	}
+}
+
+func TestOverlappingLoadTests(t *testing.T) {
+	period := api.HealthPeriod{Status: db.HealthUnhealthy, Start: at(4), End: at(6)}
+	loadtests := []db.LoadTest{
+		{ID: "before", StartTime: at(1), EndTime: at(2)},
+		{ID: "into", StartTime: at(3), EndTime: at(5)},
+		{ID: "during", StartTime: at(4.5), EndTime: at(5)},
+		{ID: "after", StartTime: at(7), EndTime: at(8)},
+		// without an end time the duration counts
+		{ID: "by duration", StartTime: at(3), Duration: 2 * 3600},
+		{ID: "short by duration", StartTime: at(3), Duration: 60},
+	}
+	got := overlappingLoadTests(loadtests, period)
+	want := []string{"into", "during", "by duration"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("overlappingLoadTests() = %v, want %v", got, want)
+	}
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+func deref(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
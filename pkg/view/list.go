@@ -0,0 +1,56 @@
+package view
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+)
+
+// listOptions - offset, limit and sort query params of list endpoints
+func listOptions(c *gin.Context, defaultSort string) (db.ListOptions, error) {
+	opts := db.ListOptions{
+		Limit: db.DefaultPageLimit,
+		Sort:  c.DefaultQuery("sort", defaultSort),
+	}
+	var err error
+	if offset := c.Query("offset"); offset != "" {
+		if opts.Offset, err = strconv.ParseInt(offset, 10, 64); err != nil || opts.Offset < 0 {
+			return opts, apierr.BadRequest("invalid offset %s", offset)
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if opts.Limit, err = strconv.ParseInt(limit, 10, 64); err != nil || opts.Limit <= 0 || opts.Limit > db.MaxPageLimit {
+			return opts, apierr.BadRequest("invalid limit %s, must be between 1 and %d", limit, db.MaxPageLimit)
+		}
+	}
+	return opts, nil
+}
+
+// timeQuery - RFC3339 time query param, zero when not given
+func timeQuery(c *gin.Context, name string) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, apierr.BadRequest("invalid %s %s", name, err.Error())
+	}
+	return t, nil
+}
+
+// boolQuery - bool query param, nil when not given
+func boolQuery(c *gin.Context, name string) (*bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, apierr.BadRequest("invalid %s %s", name, value)
+	}
+	return &b, nil
+}
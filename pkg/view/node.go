@@ -0,0 +1,51 @@
+package view
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+)
+
+const defaultNodeHistory = 100
+
+// GetNodeGroupNodes - nodes of a node group including the ones which left, and
+// the latest node events, as many as the history query param (default 100)
+func (v View) GetNodeGroupNodes(c *gin.Context) {
+	id := c.Param("id")
+	ng, err := v.d.GetNodeGroupByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if ng.ProjectID != currentProject(c).ID && !ng.Shared {
+		apierr.Respond(c, apierr.Forbidden("node group belongs to another project"))
+		return
+	}
+
+	limit := int64(defaultNodeHistory)
+	if history := c.Query("history"); history != "" {
+		if limit, err = strconv.ParseInt(history, 10, 64); err != nil || limit < 0 || limit > db.MaxPageLimit {
+			apierr.Respond(c, apierr.BadRequest("invalid history %s, must be between 0 and %d", history, db.MaxPageLimit))
+			return
+		}
+	}
+
+	nodes, err := v.d.ListNodeByNodeGroup(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	result := api.NodeGroupNodes{NodeGroupID: id, Nodes: *nodes, History: []db.NodeEvent{}}
+	if limit > 0 {
+		events, err := v.d.ListNodeEvent(id, limit)
+		if err != nil {
+			apierr.Respond(c, err)
+			return
+		}
+		result.History = *events
+	}
+	c.JSON(200, result)
+}
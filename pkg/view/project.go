@@ -0,0 +1,192 @@
+package view
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/auth"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+)
+
+const (
+	ProjectHeader = api.ProjectHeader
+	projectKey    = "project"
+)
+
+// ProjectScope - resolve the project of the request from the X-Project-ID
+// header or the project_id query param, only members and admins get through
+func (v View) ProjectScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(ProjectHeader)
+		if id == "" {
+			id = c.Query("project_id")
+		}
+		if id == "" {
+			apierr.Abort(c, apierr.BadRequest("project required, set the %s header", ProjectHeader))
+			return
+		}
+
+		project, err := v.d.GetProjectByID(id)
+		if err != nil {
+			apierr.Abort(c, err)
+			return
+		}
+		user := auth.CurrentUser(c)
+		if !project.IsMember(user.ID) && !user.HasRole(db.RoleAdmin) {
+			apierr.Abort(c, apierr.Forbidden("not a member of project %s", project.Name))
+			return
+		}
+
+		c.Set(projectKey, project)
+		c.Next()
+	}
+}
+
+func currentProject(c *gin.Context) *db.Project {
+	project, _ := c.Get(projectKey)
+	p, _ := project.(*db.Project)
+	return p
+}
+
+func (v View) CreateProject(c *gin.Context) {
+	req := api.CreateProjectRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	project := req.Project()
+
+	user := auth.CurrentUser(c)
+	project.CreatedBy = user.ID
+	project.Members = []string{user.ID}
+
+	result, err := v.d.CreateProject(&project)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Create, audit.Project, result.ID, nil, result)
+	c.JSON(200, result)
+}
+
+func (v View) GetProject(c *gin.Context) {
+	id := c.Param("id")
+	result, err := v.d.GetProjectByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	user := auth.CurrentUser(c)
+	if !result.IsMember(user.ID) && !user.HasRole(db.RoleAdmin) {
+		apierr.Respond(c, apierr.Forbidden("not a member of project %s", result.Name))
+		return
+	}
+	c.JSON(200, result)
+}
+
+// ListProjects - projects of the user, admins see all projects
+func (v View) ListProjects(c *gin.Context) {
+	user := auth.CurrentUser(c)
+	var results *[]db.Project
+	var err error
+	if user.HasRole(db.RoleAdmin) {
+		results, err = v.d.ListProject()
+	} else {
+		results, err = v.d.ListProjectByMember(user.ID)
+	}
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	c.JSON(200, results)
+}
+
+func (v View) UpdateProject(c *gin.Context) {
+	id := c.Param("id")
+	req := api.UpdateProjectRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+
+	current, err := v.d.GetProjectByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	result, err := v.d.UpdateProject(id, req.Update())
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Update, audit.Project, id, current, result)
+	c.JSON(200, result)
+}
+
+func (v View) DeleteProject(c *gin.Context) {
+	id := c.Param("id")
+	current, err := v.d.GetProjectByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	nodeGroups, loadtests, err := v.d.CountProjectResources(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if nodeGroups > 0 || loadtests > 0 {
+		apierr.Respond(c, apierr.Conflict("project %s still owns %d node groups and %d load tests", current.Name, nodeGroups, loadtests))
+		return
+	}
+	err = v.d.DeleteProject(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Delete, audit.Project, id, current, nil)
+	c.JSON(200, map[string]string{"status": "ok"})
+}
+
+func (v View) AddProjectMember(c *gin.Context) {
+	id := c.Param("id")
+	req := api.AddProjectMemberRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+
+	if _, err := v.d.GetUserByID(req.UserID); err != nil {
+		apierr.Respond(c, apierr.Invalid("user_id", "user %s not found", req.UserID))
+		return
+	}
+	current, err := v.d.GetProjectByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	result, err := v.d.AddProjectMember(id, req.UserID)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Update, audit.Project, id, current, result)
+	c.JSON(200, result)
+}
+
+func (v View) RemoveProjectMember(c *gin.Context) {
+	id := c.Param("id")
+	current, err := v.d.GetProjectByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	result, err := v.d.RemoveProjectMember(id, c.Param("user_id"))
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Update, audit.Project, id, current, result)
+	c.JSON(200, result)
+}
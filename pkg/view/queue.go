@@ -0,0 +1,110 @@
+package view
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/auth"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ListQueue - queued load tests of the project in the order they start,
+// optionally only the ones of the namespace query param
+func (v View) ListQueue(c *gin.Context) {
+	results, err := v.d.ListQueuedLoadTest(currentProject(c).ID, c.Query("namespace"))
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	c.JSON(200, results)
+}
+
+// ReorderQueuedLoadTest - change the priority of a queued load test
+func (v View) ReorderQueuedLoadTest(c *gin.Context) {
+	req := api.ReorderQueuedLoadTestRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	current, ok := v.queuedLoadTest(c)
+	if !ok {
+		return
+	}
+
+	result, err := v.d.UpdateLoadTestIfStatus(current.ID, "queued", bson.M{"priority": req.Priority})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		apierr.Respond(c, apierr.Conflict("load test is no longer queued"))
+		return
+	}
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Update, audit.LoadTest, current.ID, current, result)
+	v.s.Dispatch(result.Namespace)
+	c.JSON(200, result)
+}
+
+// CancelQueuedLoadTest - remove a load test from the queue before it started
+func (v View) CancelQueuedLoadTest(c *gin.Context) {
+	current, ok := v.queuedLoadTest(c)
+	if !ok {
+		return
+	}
+
+	result, err := v.cancelQueued(c, current)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	c.JSON(200, result)
+}
+
+// cancelQueued - cancel a load test which didn't start yet, a conflict when it
+// is no longer queued
+func (v View) cancelQueued(c *gin.Context, current *db.LoadTest) (*db.LoadTest, error) {
+	result, err := v.d.UpdateLoadTestIfStatus(current.ID, "queued", bson.M{"status": "cancelled", "end_time": time.Now()})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, apierr.Conflict("load test is no longer queued")
+	}
+	if err != nil {
+		return nil, err
+	}
+	v.audit(c, audit.Cancel, audit.LoadTest, current.ID, current, result)
+	v.hub.Status(result.ID, result.Status)
+	// followers get the empty summary of a load test which never ran
+	if summary, err := v.d.FetchLoadTestResults(result.ID); err == nil {
+		v.hub.Complete(result.ID, summary)
+	}
+	v.s.Dispatch(result.Namespace)
+	return result, nil
+}
+
+// queuedLoadTest - the queued load test of the id param when the user can
+// manage it, responds with the error otherwise
+func (v View) queuedLoadTest(c *gin.Context) (*db.LoadTest, bool) {
+	current, err := v.d.GetLoadTestByID(c.Param("id"))
+	if err != nil {
+		apierr.Respond(c, err)
+		return nil, false
+	}
+	if current.ProjectID != currentProject(c).ID {
+		apierr.Respond(c, apierr.Forbidden("load test belongs to another project"))
+		return nil, false
+	}
+	if !canManageLoadTest(auth.CurrentUser(c), current) {
+		apierr.Respond(c, apierr.Forbidden("only admins can change load tests of other users"))
+		return nil, false
+	}
+	if current.Status != "queued" {
+		apierr.Respond(c, apierr.Conflict("load test is %s, not queued", current.Status))
+		return nil, false
+	}
+	return current, true
+}
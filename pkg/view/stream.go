@@ -0,0 +1,68 @@
+package view
+
+import (
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/live"
+)
+
+// keepAliveInterval - comments are sent this often so that proxies don't close
+// a stream of a load test which is quiet
+const keepAliveInterval = 15 * time.Second
+
+// StreamLoadTest - server sent events of a load test, the current status and
+// stats first, then stats as heartbeats are processed, status changes and a
+// final summary once the load test completes
+func (v View) StreamLoadTest(c *gin.Context) {
+	id := c.Param("id")
+	lt, err := v.d.GetLoadTestByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if lt.ProjectID != currentProject(c).ID {
+		apierr.Respond(c, apierr.Forbidden("load test belongs to another project"))
+		return
+	}
+
+	// subscribe before sending the current state so nothing is missed between
+	events, unsubscribe := v.hub.Subscribe(id)
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent(live.EventStatus, live.Event{Type: live.EventStatus, LoadTestID: id, Data: live.StatusChange{Status: lt.Status}})
+
+	// a finished load test only gets its summary
+	if !lt.IsRunning() && lt.Status != "queued" {
+		summary, err := v.d.FetchLoadTestResults(id)
+		if err != nil {
+			c.SSEvent("error", apierr.From(err))
+			return
+		}
+		c.SSEvent(live.EventSummary, live.Event{Type: live.EventSummary, LoadTestID: id, Data: summary})
+		return
+	}
+	if stats, ok := v.hub.Snapshot(id); ok {
+		c.SSEvent(live.EventStats, live.Event{Type: live.EventStats, LoadTestID: id, Data: stats})
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-keepAlive.C:
+			w.Write([]byte(": keep-alive\n\n"))
+			return true
+		case e := <-events:
+			c.SSEvent(e.Type, e)
+			return !e.IsFinal()
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package view
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (v View) ListUsers(c *gin.Context) {
+	opts, err := listOptions(c, "created_at")
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	results, err := v.d.ListUserPage(opts)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	c.JSON(200, results)
+}
+
+func (v View) UpdateUserRole(c *gin.Context) {
+	id := c.Param("id")
+	req := api.UpdateUserRoleRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+
+	current, err := v.d.GetUserByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	result, err := v.d.UpdateUser(id, bson.M{"role": req.Role})
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.RoleChange, audit.User, id, current, result)
+	c.JSON(200, result)
+}
@@ -1,32 +1,104 @@
 package view
 
 import (
+	"errors"
+	"slices"
+
 	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/api"
+	"github.com/mridulganga/dlt-manager/pkg/apierr"
+	"github.com/mridulganga/dlt-manager/pkg/audit"
+	"github.com/mridulganga/dlt-manager/pkg/auth"
 	"github.com/mridulganga/dlt-manager/pkg/db"
-	"github.com/mridulganga/dlt-manager/pkg/mqttlib"
+	"github.com/mridulganga/dlt-manager/pkg/live"
+	"github.com/mridulganga/dlt-manager/pkg/proc"
+	"github.com/mridulganga/dlt-manager/pkg/sched"
+	"github.com/mridulganga/dlt-manager/pkg/transport"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type View struct {
-	d *db.DB
-	m mqttlib.MqttClient
+	d          *db.DB
+	m          transport.Transport
+	p          *proc.Processor
+	a          *auth.Auth
+	au         *audit.Auditor
+	hub        *live.Hub
+	s          *sched.Scheduler
+	namespaces []transport.Namespace
 }
 
-func NewView(database *db.DB, mqttClient mqttlib.MqttClient) View {
+func NewView(database *db.DB, t transport.Transport, processor *proc.Processor, a *auth.Auth, auditor *audit.Auditor, hub *live.Hub, scheduler *sched.Scheduler, namespaces []transport.Namespace) View {
 	return View{
-		d: database,
-		m: mqttClient,
+		d:          database,
+		m:          t,
+		p:          processor,
+		a:          a,
+		au:         auditor,
+		hub:        hub,
+		s:          scheduler,
+		namespaces: namespaces,
 	}
 }
 
+// audit - record an action of the user making the request
+func (v View) audit(c *gin.Context, action string, targetType string, targetId string, before any, after any) {
+	entry := db.AuditEntry{
+		ActorType:  db.ActorTypeUser,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetId,
+	}
+	if user := auth.CurrentUser(c); user != nil {
+		entry.Actor = user.ID
+	}
+	if apiKey := auth.CurrentAPIKey(c); apiKey != nil {
+		entry.ActorType = db.ActorTypeAPIKey
+		entry.APIKeyID = apiKey.ID
+	}
+	v.au.Record(entry, before, after)
+}
+
+// namespace - resolve a namespace given in a request, the first namespace of
+// the manager is used when none is given
+func (v View) namespace(name string) (transport.Namespace, error) {
+	if name == "" {
+		return v.namespaces[0], nil
+	}
+	ns := transport.Namespace(name)
+	if !transport.HasNamespace(v.namespaces, ns) {
+		return "", apierr.Invalid("namespace", "namespace %s is not served by this manager", name)
+	}
+	return ns, nil
+}
+
 func (v View) CreateNodeGroup(c *gin.Context) {
-	ng := db.NodeGroup{}
-	c.BindJSON(&ng)
+	req := api.CreateNodeGroupRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	ng := req.NodeGroup()
+
+	ns, err := v.namespace(ng.Namespace)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if err := ns.ValidateTopic(ng.Topic); err != nil {
+		apierr.Respond(c, apierr.Invalid("topic", err.Error()))
+		return
+	}
+	ng.Namespace = string(ns)
+	ng.ProjectID = currentProject(c).ID
+
 	result, err := v.d.CreateNodeGroup(&ng)
 	if err != nil {
-		c.JSON(400, map[string]string{"error": err.Error()})
+		apierr.Respond(c, err)
 		return
 	}
+	v.audit(c, audit.Create, audit.NodeGroup, result.ID, nil, result)
 	c.JSON(200, result)
 }
 
@@ -34,7 +106,11 @@ func (v View) GetNodeGroup(c *gin.Context) {
 	id := c.Param("id")
 	result, err := v.d.GetNodeGroupByID(id)
 	if err != nil {
-		c.JSON(400, map[string]string{"error": err.Error()})
+		apierr.Respond(c, err)
+		return
+	}
+	if result.ProjectID != currentProject(c).ID && !result.Shared {
+		apierr.Respond(c, apierr.Forbidden("node group belongs to another project"))
 		return
 	}
 	c.JSON(200, result)
@@ -42,63 +118,163 @@ func (v View) GetNodeGroup(c *gin.Context) {
 
 func (v View) UpdateNodeGroup(c *gin.Context) {
 	id := c.Param("id")
-	ng := bson.M{}
-	c.BindJSON(&ng)
+	req := api.UpdateNodeGroupRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+
+	current, err := v.d.GetNodeGroupByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if current.ProjectID != currentProject(c).ID {
+		apierr.Respond(c, apierr.Forbidden("node group belongs to another project"))
+		return
+	}
+
+	// moving a node group must keep its topic inside its namespace
+	ng := req.Update()
+	if req.Topic != nil || req.Namespace != nil {
+		topic, name := current.Topic, current.Namespace
+		if req.Topic != nil {
+			topic = *req.Topic
+		}
+		if req.Namespace != nil {
+			name = *req.Namespace
+		}
+		ns, err := v.namespace(name)
+		if err != nil {
+			apierr.Respond(c, err)
+			return
+		}
+		if err := ns.ValidateTopic(topic); err != nil {
+			apierr.Respond(c, apierr.Invalid("topic", err.Error()))
+			return
+		}
+		ng["namespace"] = string(ns)
+	}
 
 	result, err := v.d.UpdateNodeGroup(id, ng)
 	if err != nil {
-		c.JSON(400, map[string]string{"error": err.Error()})
+		apierr.Respond(c, err)
 		return
 	}
+	v.audit(c, audit.Update, audit.NodeGroup, id, current, result)
 	c.JSON(200, result)
 }
 
 func (v View) DeleteNodeGroup(c *gin.Context) {
 	id := c.Param("id")
-	err := v.d.DeleteNodeGroup(id)
+	current, err := v.d.GetNodeGroupByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if current.ProjectID != currentProject(c).ID {
+		apierr.Respond(c, apierr.Forbidden("node group belongs to another project"))
+		return
+	}
+	err = v.d.DeleteNodeGroup(id)
 	if err != nil {
-		c.JSON(400, map[string]string{"error": err.Error()})
+		apierr.Respond(c, err)
+		return
+	}
+	if err := v.d.DeleteNodesByNodeGroup(id); err != nil {
+		apierr.Respond(c, err)
 		return
 	}
+	if err := v.d.DeleteHealthEventsByNodeGroup(id); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Delete, audit.NodeGroup, id, current, nil)
 	c.JSON(200, map[string]string{"status": "ok"})
 }
 
+// ListNodeGroups - paginated with offset, limit and sort, filtered by the
+// namespace, is_healthy, health_status, pending, cordoned and config_drift
+// query params
 func (v View) ListNodeGroups(c *gin.Context) {
-	results, err := v.d.ListNodeGroup()
+	opts, err := listOptions(c, "_id")
 	if err != nil {
-		c.JSON(400, map[string]string{"error": err.Error()})
+		apierr.Respond(c, err)
+		return
+	}
+	filter := db.NodeGroupFilter{
+		ProjectID:    currentProject(c).ID,
+		HealthStatus: c.Query("health_status"),
+	}
+	if namespace, ok := c.GetQuery("namespace"); ok {
+		filter.Namespace = &namespace
+	}
+	if filter.IsHealthy, err = boolQuery(c, "is_healthy"); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if filter.Pending, err = boolQuery(c, "pending"); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if filter.Cordoned, err = boolQuery(c, "cordoned"); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if filter.ConfigDrift, err = boolQuery(c, "config_drift"); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+
+	results, err := v.d.ListNodeGroupPage(filter, opts)
+	if err != nil {
+		apierr.Respond(c, err)
 		return
 	}
 	c.JSON(200, results)
 }
 
 func (v View) CreateLoadTest(c *gin.Context) {
-	lt := db.LoadTest{}
-	c.BindJSON(&lt)
-	result, err := v.d.CreateLoadTest(&lt)
-	if err != nil {
-		c.JSON(400, map[string]string{"error": err.Error()})
+	req := api.CreateLoadTestRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Respond(c, err)
 		return
 	}
+	lt := req.LoadTest()
 
-	// get all nodegroups
-	nodegroups, err := v.d.ListNodeGroup()
+	ns, err := v.namespace(lt.Namespace)
 	if err != nil {
-		c.JSON(400, map[string]string{"error": err.Error()})
+		apierr.Respond(c, err)
 		return
 	}
+	lt.Namespace = string(ns)
+	lt.CreatedBy = auth.CurrentUser(c).ID
 
-	// trigger load test in all node groups
-	for _, ng := range *nodegroups {
-		v.m.Publish(ng.Topic, map[string]any{
-			"action":       "start_loadtest",
-			"load_test_id": result.ID,
-			"plugin_data":  lt.Logic,
-			"duration":     lt.Duration,
-			"tps":          lt.TPS,
-		})
+	project := currentProject(c)
+	lt.ProjectID = project.ID
+	if err := v.checkQuota(project, &lt); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+
+	if err := v.checkCapacity(&lt); err != nil {
+		apierr.Respond(c, err)
+		return
 	}
+	lt.Status = "queued"
 
+	result, err := v.d.CreateLoadTest(&lt)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	v.audit(c, audit.Create, audit.LoadTest, result.ID, nil, result)
+
+	v.s.Dispatch(result.Namespace)
+	if result, err = v.d.GetLoadTestByID(result.ID); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
 	c.JSON(200, result)
 }
 
@@ -106,7 +282,11 @@ func (v View) GetLoadTest(c *gin.Context) {
 	id := c.Param("id")
 	result, err := v.d.GetLoadTestByID(id)
 	if err != nil {
-		c.JSON(400, map[string]string{"error": err.Error()})
+		apierr.Respond(c, err)
+		return
+	}
+	if result.ProjectID != currentProject(c).ID {
+		apierr.Respond(c, apierr.Forbidden("load test belongs to another project"))
 		return
 	}
 	c.JSON(200, result)
@@ -114,60 +294,280 @@ func (v View) GetLoadTest(c *gin.Context) {
 
 func (v View) UpdateLoadTest(c *gin.Context) {
 	id := c.Param("id")
-	lt := bson.M{}
-	c.BindJSON(&lt)
+	req := api.UpdateLoadTestRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+
+	current, err := v.d.GetLoadTestByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	user := auth.CurrentUser(c)
+	if current.ProjectID != currentProject(c).ID {
+		apierr.Respond(c, apierr.Forbidden("load test belongs to another project"))
+		return
+	}
+	if !canManageLoadTest(user, current) {
+		apierr.Respond(c, apierr.Forbidden("only admins can change load tests of other users"))
+		return
+	}
+	lt := req.Update()
+	if !user.HasRole(db.RoleAdmin) {
+		delete(lt, "created_by")
+	}
+	// the new tps has to fit the same limits as a new load test
+	if req.TPS != nil && *req.TPS != current.TPS {
+		changed := *current
+		changed.TPS = *req.TPS
+		if err := v.checkTPSQuota(currentProject(c), &changed); err != nil {
+			apierr.Respond(c, err)
+			return
+		}
+		if err := v.checkCapacity(&changed); err != nil {
+			apierr.Respond(c, err)
+			return
+		}
+	}
 
 	result, err := v.d.UpdateLoadTest(id, lt)
 	if err != nil {
-		c.JSON(400, map[string]string{"error": err.Error()})
+		apierr.Respond(c, err)
 		return
 	}
+	v.audit(c, audit.Update, audit.LoadTest, id, current, result)
 	c.JSON(200, result)
 }
 
 func (v View) DeleteLoadTest(c *gin.Context) {
 	id := c.Param("id")
-	err := v.d.DeleteNodeGroup(id)
+	current, err := v.d.GetLoadTestByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if current.ProjectID != currentProject(c).ID {
+		apierr.Respond(c, apierr.Forbidden("load test belongs to another project"))
+		return
+	}
+	if !canManageLoadTest(auth.CurrentUser(c), current) {
+		apierr.Respond(c, apierr.Forbidden("only admins can delete load tests of other users"))
+		return
+	}
+
+	err = v.d.DeleteLoadTest(id)
 	if err != nil {
-		c.JSON(400, map[string]string{"error": err.Error()})
+		apierr.Respond(c, err)
 		return
 	}
+	v.audit(c, audit.Delete, audit.LoadTest, id, current, nil)
 	c.JSON(200, map[string]string{"status": "ok"})
 }
 
+// ListLoadTests - paginated with offset, limit and sort, filtered by the status,
+// created_by, from, to (start time) and q (description) query params
 func (v View) ListLoadTests(c *gin.Context) {
-	results, err := v.d.ListLoadTest()
+	opts, err := listOptions(c, "-start_time")
 	if err != nil {
-		c.JSON(400, map[string]string{"error": err.Error()})
+		apierr.Respond(c, err)
+		return
+	}
+	filter := db.LoadTestFilter{
+		ProjectID: currentProject(c).ID,
+		Status:    c.Query("status"),
+		CreatedBy: c.Query("created_by"),
+		Text:      c.Query("q"),
+	}
+	if filter.From, err = timeQuery(c, "from"); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if filter.To, err = timeQuery(c, "to"); err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+
+	results, err := v.d.ListLoadTestPage(filter, opts)
+	if err != nil {
+		apierr.Respond(c, err)
 		return
 	}
 	c.JSON(200, results)
 }
 
+// StopLoadTest - stop the load test given by the id query param, admins can
+// leave it out to stop whatever is running in the namespace. A queued load
+// test is cancelled before it starts
 func (v View) StopLoadTest(c *gin.Context) {
-	// get all nodegroups
-	nodegroups, err := v.d.ListNodeGroup()
+	user := auth.CurrentUser(c)
+	id := c.Query("id")
+	namespace := c.Query("namespace")
+	var lt *db.LoadTest
+	if id != "" {
+		var err error
+		lt, err = v.d.GetLoadTestByID(id)
+		if err != nil {
+			apierr.Respond(c, err)
+			return
+		}
+		if lt.ProjectID != currentProject(c).ID {
+			apierr.Respond(c, apierr.Forbidden("load test belongs to another project"))
+			return
+		}
+		if !canManageLoadTest(user, lt) {
+			apierr.Respond(c, apierr.Forbidden("only admins can stop load tests of other users"))
+			return
+		}
+		if lt.Status == "queued" {
+			result, err := v.cancelQueued(c, lt)
+			if err != nil {
+				apierr.Respond(c, err)
+				return
+			}
+			c.JSON(200, map[string]string{"status": result.Status})
+			return
+		}
+		if !lt.IsRunning() {
+			apierr.Respond(c, apierr.Conflict("load test is %s", lt.Status))
+			return
+		}
+		namespace = lt.Namespace
+	} else if !user.HasRole(db.RoleAdmin) {
+		apierr.Respond(c, apierr.Forbidden("only admins can stop all load tests"))
+		return
+	}
+
+	ns, err := v.namespace(namespace)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+
+	// get all nodegroups of the project in the namespace
+	nodegroups, err := v.d.ListNodeGroupForProjectByNamespace(currentProject(c).ID, string(ns))
 	if err != nil {
-		c.JSON(400, map[string]string{"error": err.Error()})
+		apierr.Respond(c, err)
 		return
 	}
 
-	// trigger stpo load test in all node groups
+	// the load tests are stopping until their node groups stopped them, they
+	// end up stopped instead of complete
+	stopping := []db.LoadTest{}
+	if lt != nil {
+		stopping = append(stopping, *lt)
+	} else {
+		running, err := v.d.ListRunningLoadTestByNamespace(string(ns))
+		if err != nil {
+			apierr.Respond(c, err)
+			return
+		}
+		for _, current := range *running {
+			if current.ProjectID == currentProject(c).ID {
+				stopping = append(stopping, current)
+			}
+		}
+	}
+	for _, current := range stopping {
+		updated, err := v.d.UpdateLoadTestIfStatus(current.ID, "running", bson.M{"status": "stopping"})
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// stopping already or ended in the meantime
+			continue
+		}
+		if err != nil {
+			apierr.Respond(c, err)
+			return
+		}
+		v.audit(c, audit.Stop, audit.LoadTest, current.ID, current, updated)
+		v.hub.Status(current.ID, updated.Status)
+	}
+
+	// trigger stop load test in the node groups running it
 	for _, ng := range *nodegroups {
+		if lt != nil && !lt.RunsOn(ng.ID) {
+			continue
+		}
 		v.m.Publish(ng.Topic, map[string]any{
-			"action": "stop_loadtest",
+			"action":       "stop_loadtest",
+			"load_test_id": id,
 		})
 	}
+	if id == "" {
+		v.audit(c, audit.Stop, audit.LoadTest, id, nil, map[string]any{"namespace": ns})
+	}
 
 	c.JSON(200, map[string]string{"status": "stopping"})
 }
 
 func (v View) GetLoadTestResults(c *gin.Context) {
 	id := c.Param("id")
+	lt, err := v.d.GetLoadTestByID(id)
+	if err != nil {
+		apierr.Respond(c, err)
+		return
+	}
+	if lt.ProjectID != currentProject(c).ID {
+		apierr.Respond(c, apierr.Forbidden("load test belongs to another project"))
+		return
+	}
 	result, err := v.d.FetchLoadTestResults(id)
 	if err != nil {
-		c.JSON(400, map[string]string{"error": err.Error()})
+		apierr.Respond(c, err)
 		return
 	}
 	c.JSON(200, result)
 }
+
+// canManageLoadTest - operators can only manage the load tests they started
+func canManageLoadTest(user *db.User, lt *db.LoadTest) bool {
+	return user.HasRole(db.RoleAdmin) || (user.HasRole(db.RoleOperator) && lt.CreatedBy == user.ID)
+}
+
+// checkQuota - refuse load tests which would take the project over its quota,
+// zero quota values are unlimited
+func (v View) checkQuota(project *db.Project, lt *db.LoadTest) error {
+	if err := v.checkTPSQuota(project, lt); err != nil {
+		return err
+	}
+	if project.Quota.MaxConcurrentTests > 0 {
+		active, err := v.d.CountActiveLoadTest(project.ID)
+		if err != nil {
+			return err
+		}
+		if active >= int64(project.Quota.MaxConcurrentTests) {
+			return apierr.New(409, apierr.CodeQuotaExceeded, "project already runs or queued %d of %d allowed load tests", active, project.Quota.MaxConcurrentTests)
+		}
+	}
+	return nil
+}
+
+// checkTPSQuota - refuse tps above the project limit
+func (v View) checkTPSQuota(project *db.Project, lt *db.LoadTest) error {
+	if project.Quota.MaxTPS > 0 && lt.TPS > project.Quota.MaxTPS {
+		return apierr.Invalid("tps", "exceeds the project limit of %v", project.Quota.MaxTPS)
+	}
+	return nil
+}
+
+// checkCapacity - refuse load tests the node groups couldn't run even when all
+// healthy, the others wait in the queue until the node groups are idle
+func (v View) checkCapacity(lt *db.LoadTest) error {
+	nodegroups, err := v.d.ListNodeGroupForProjectByNamespace(lt.ProjectID, lt.Namespace)
+	if err != nil {
+		return err
+	}
+	schedulable := sched.Schedulable(*nodegroups)
+	if len(lt.NodeGroups) > 0 {
+		bound := []db.NodeGroup{}
+		for _, ngId := range lt.NodeGroups {
+			i := slices.IndexFunc(schedulable, func(ng db.NodeGroup) bool { return ng.ID == ngId })
+			if i < 0 {
+				return apierr.Invalid("node_groups", "node group %s can't run load tests of the project in namespace %s", ngId, lt.Namespace)
+			}
+			bound = append(bound, schedulable[i])
+		}
+		schedulable = bound
+	}
+	return sched.CheckCapacity(schedulable, lt)
+}
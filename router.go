@@ -0,0 +1,101 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/auth"
+	"github.com/mridulganga/dlt-manager/pkg/db"
+	"github.com/mridulganga/dlt-manager/pkg/openapi"
+	"github.com/mridulganga/dlt-manager/pkg/view"
+)
+
+// setupRouter - routes of the api, every route has to be documented in the
+// openapi spec
+func setupRouter(vi view.View, a *auth.Auth) *gin.Engine {
+	r := gin.New()
+	r.Use(
+		gin.LoggerWithWriter(gin.DefaultWriter, "/"),
+		gin.Recovery(),
+	)
+
+	r.GET("/", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"status": "running",
+		})
+	})
+	r.GET("/api/openapi.json", openapi.Handler)
+	r.GET("/api/docs", openapi.DocsHandler)
+
+	r.POST("/auth/register", vi.Register)
+	r.POST("/auth/login", vi.Login)
+	r.POST("/auth/logout", a.Middleware(), auth.RequireSession(), vi.Logout)
+	r.GET("/auth/me", a.Middleware(), vi.Me)
+
+	// viewers can read everything, the handlers check ownership for operators
+	g := r.Group("/api", a.Middleware(), auth.RequireRole(db.RoleViewer))
+	operator := auth.RequireRole(db.RoleOperator)
+	admin := auth.RequireRole(db.RoleAdmin)
+
+	g.PUT("/projects", operator, vi.CreateProject)
+	g.GET("/projects", vi.ListProjects)
+	g.GET("/projects/:id", vi.GetProject)
+	g.PATCH("/projects/:id", admin, vi.UpdateProject)
+	g.DELETE("/projects/:id", admin, vi.DeleteProject)
+	g.PUT("/projects/:id/members", admin, vi.AddProjectMember)
+	g.DELETE("/projects/:id/members/:user_id", admin, vi.RemoveProjectMember)
+
+	// node groups and load tests belong to the project given with X-Project-ID
+	pg := g.Group("", vi.ProjectScope())
+
+	pg.GET("/ngs", vi.ListNodeGroups)
+	pg.GET("/ngs/:id", vi.GetNodeGroup)
+	pg.GET("/ngs/:id/nodes", vi.GetNodeGroupNodes)
+	pg.GET("/ngs/:id/health", vi.GetNodeGroupHealth)
+	pg.GET("/ngs/:id/config", vi.GetNodeGroupConfig)
+	pg.PUT("/ngs/:id/config", admin, vi.SetNodeGroupConfig)
+	pg.PATCH("/ngs/:id", admin, vi.UpdateNodeGroup)
+	pg.PUT("/ngs", admin, vi.CreateNodeGroup)
+	pg.DELETE("/ngs/:id", admin, vi.DeleteNodeGroup)
+	pg.PUT("/ngs/:id/approve", admin, vi.ApproveNodeGroup)
+	pg.PUT("/ngs/:id/cordon", admin, vi.CordonNodeGroup)
+	pg.PUT("/ngs/:id/uncordon", admin, vi.UncordonNodeGroup)
+	pg.PUT("/ngs/:id/drain", admin, vi.DrainNodeGroup)
+
+	pg.GET("/loadtests", vi.ListLoadTests)
+	pg.GET("/loadtests/:id", vi.GetLoadTest)
+	pg.PATCH("/loadtests/:id", operator, vi.UpdateLoadTest)
+	pg.PUT("/loadtests", operator, vi.CreateLoadTest)
+	pg.DELETE("/loadtests/:id", operator, vi.DeleteLoadTest)
+
+	pg.PUT("/loadtests/stop", operator, vi.StopLoadTest)
+	pg.GET("/loadtests/:id/results", vi.GetLoadTestResults)
+	pg.GET("/loadtests/:id/stream", vi.StreamLoadTest)
+
+	// load tests wait in the queue until their node groups are idle
+	pg.GET("/queue", vi.ListQueue)
+	pg.PATCH("/queue/:id", operator, vi.ReorderQueuedLoadTest)
+	pg.DELETE("/queue/:id", operator, vi.CancelQueuedLoadTest)
+
+	g.GET("/deadletters", admin, vi.ListDeadLetters)
+	g.GET("/deadletters/:id", admin, vi.GetDeadLetter)
+	g.PUT("/deadletters/:id/replay", admin, vi.ReplayDeadLetter)
+	g.DELETE("/deadletters/:id", admin, vi.DeleteDeadLetter)
+	g.DELETE("/deadletters", admin, vi.PurgeDeadLetters)
+
+	// credentials can only be managed after logging in with a password
+	session := auth.RequireSession()
+	g.PUT("/apikeys", session, vi.CreateAPIKey)
+	g.GET("/apikeys", session, vi.ListAPIKeys)
+	g.DELETE("/apikeys/:id", session, vi.RevokeAPIKey)
+
+	// node groups register themselves with enrollment tokens
+	g.PUT("/enrollmenttokens", admin, vi.CreateEnrollmentToken)
+	g.GET("/enrollmenttokens", admin, vi.ListEnrollmentTokens)
+	g.DELETE("/enrollmenttokens/:id", admin, vi.RevokeEnrollmentToken)
+
+	g.GET("/audit", admin, vi.ListAudit)
+
+	g.GET("/users", admin, vi.ListUsers)
+	g.PATCH("/users/:id/role", admin, vi.UpdateUserRole)
+
+	return r
+}
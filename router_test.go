@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mridulganga/dlt-manager/pkg/auth"
+	"github.com/mridulganga/dlt-manager/pkg/openapi"
+	"github.com/mridulganga/dlt-manager/pkg/view"
+)
+
+func TestRoutesMatchSpec(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := setupRouter(view.View{}, auth.NewAuth(nil, "secret", time.Hour))
+	if err := openapi.CheckRoutes(r.Routes()); err != nil {
+		t.Fatal(err)
+	}
+}